@@ -42,6 +42,16 @@ func handleEarly(command string, args []string) (bool, error) {
 		}
 		fmt.Printf("version: %s\n", vers)
 		return true, nil
+	case commands.Agent:
+		return true, app.Agent(os.Stdout)
+	case commands.Lock:
+		return true, app.Lock()
+	case commands.KeyFile:
+		return true, app.KeyFile(os.Stdout, args)
+	case commands.Shard:
+		return true, app.Shard(os.Stdout, args, app.ShardReader())
+	case commands.ClipClear:
+		return true, app.ClipClear(os.Stdin)
 	}
 	return false, nil
 }
@@ -50,6 +60,7 @@ func run() error {
 	if err := config.Parse(app.ConfigLoader{}); err != nil {
 		return err
 	}
+	defer config.ForgetPromptedKeys()
 	args := os.Args
 	if len(args) < 2 {
 		return errors.New("requires subcommand")
@@ -74,6 +85,12 @@ func run() error {
 	switch command {
 	case commands.Health:
 		return app.Health(p)
+	case commands.Audit:
+		return app.Audit(p)
+	case commands.Stale:
+		return app.Stale(p)
+	case commands.AuditLog:
+		return app.AuditLog(p)
 	case commands.ReKey:
 		return app.ReKey(p)
 	case commands.List, commands.Groups, commands.Fields:
@@ -97,6 +114,8 @@ func run() error {
 		return app.JSON(p)
 	case commands.Show, commands.Clip:
 		return app.ShowClip(p, command == commands.Show)
+	case commands.QR:
+		return app.QR(p)
 	case commands.Conv:
 		return app.Conv(p)
 	case commands.TOTP:
@@ -0,0 +1,117 @@
+// Package agent handles caching unlocked keys in a background process
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+const (
+	getCommand    = "get"
+	setCommand    = "set"
+	removeCommand = "remove"
+	lockCommand   = "lock"
+	socketName    = "agent.sock"
+	dialTimeout   = 2 * time.Second
+)
+
+// ErrNotRunning indicates the agent socket could not be reached
+var ErrNotRunning = errors.New("agent is not running")
+
+type (
+	request struct {
+		Command string `json:"command"`
+		ID      string `json:"id,omitempty"`
+		Data    []byte `json:"data,omitempty"`
+	}
+	response struct {
+		Error string `json:"error,omitempty"`
+		Data  []byte `json:"data,omitempty"`
+	}
+)
+
+// DefaultSocket is the socket location used when none is configured
+func DefaultSocket() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), fmt.Sprintf("lockbox-%d", os.Getuid()))
+	} else {
+		dir = filepath.Join(dir, "lockbox")
+	}
+	return filepath.Join(dir, socketName)
+}
+
+// Get will request the cached key for an identifier from the agent
+func Get(socket, id string) ([]byte, error) {
+	return send(socket, request{Command: getCommand, ID: id})
+}
+
+// Set will cache a key for an identifier within the agent
+func Set(socket, id string, data []byte) error {
+	_, err := send(socket, request{Command: setCommand, ID: id, Data: data})
+	return err
+}
+
+// Remove will purge a single identifier from the agent
+func Remove(socket, id string) error {
+	_, err := send(socket, request{Command: removeCommand, ID: id})
+	return err
+}
+
+// Lock will purge all cached keys from the agent
+func Lock(socket string) error {
+	_, err := send(socket, request{Command: lockCommand})
+	return err
+}
+
+func checkOwner(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return errors.New("unable to read socket ownership")
+	}
+	if int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("%s is not owned by the current user", path)
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		return fmt.Errorf("%s is accessible by other users", path)
+	}
+	return nil
+}
+
+func send(socket string, req request) ([]byte, error) {
+	if err := checkOwner(socket); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotRunning
+		}
+		return nil, err
+	}
+	conn, err := net.DialTimeout("unix", socket, dialTimeout)
+	if err != nil {
+		return nil, ErrNotRunning
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(dialTimeout)); err != nil {
+		return nil, err
+	}
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, err
+	}
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return resp.Data, nil
+}
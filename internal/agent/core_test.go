@@ -0,0 +1,141 @@
+package agent_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/enckse/lockbox/internal/agent"
+)
+
+func newServer(t *testing.T, idle, absolute time.Duration) (*agent.Server, string) {
+	socket := filepath.Join(t.TempDir(), "sub", "agent.sock")
+	s, err := agent.NewServer(socket, idle, absolute)
+	if err != nil {
+		t.Fatalf("invalid error: %v", err)
+	}
+	go s.Run()
+	t.Cleanup(func() {
+		s.Close()
+	})
+	return s, socket
+}
+
+func TestNotRunning(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	if _, err := agent.Get(socket, "abc"); !errors.Is(err, agent.ErrNotRunning) {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := agent.Lock(socket); !errors.Is(err, agent.ErrNotRunning) {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestNewServer(t *testing.T) {
+	if _, err := agent.NewServer("", 0, 1); err == nil || err.Error() != "agent timeouts must be > 0" {
+		t.Errorf("invalid error: %v", err)
+	}
+	_, socket := newServer(t, time.Minute, time.Minute)
+	info, err := os.Stat(socket)
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("invalid socket: %v %v", info, err)
+	}
+	info, err = os.Stat(filepath.Dir(socket))
+	if err != nil || info.Mode().Perm() != 0o700 {
+		t.Errorf("invalid socket dir: %v %v", info, err)
+	}
+	if _, err := agent.NewServer(socket, time.Minute, time.Minute); err == nil || !strings.HasPrefix(err.Error(), "agent already running") {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestGetSet(t *testing.T) {
+	_, socket := newServer(t, time.Minute, time.Minute)
+	if _, err := agent.Get(socket, "abc"); err == nil || err.Error() != "no cached key" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := agent.Set(socket, "abc", nil); err == nil || err.Error() != "no key data given" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := agent.Set(socket, "", []byte("xyz")); err == nil || err.Error() != "no key identifier given" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := agent.Set(socket, "abc", []byte("xyz")); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := agent.Set(socket, "def", []byte("123")); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	b, err := agent.Get(socket, "abc")
+	if err != nil || string(b) != "xyz" {
+		t.Errorf("invalid get: %s %v", string(b), err)
+	}
+	if err := agent.Remove(socket, "abc"); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := agent.Get(socket, "abc"); err == nil || err.Error() != "no cached key" {
+		t.Errorf("invalid error: %v", err)
+	}
+	b, err = agent.Get(socket, "def")
+	if err != nil || string(b) != "123" {
+		t.Errorf("invalid get: %s %v", string(b), err)
+	}
+	if err := agent.Lock(socket); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := agent.Get(socket, "def"); err == nil || err.Error() != "no cached key" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	_, socket := newServer(t, 200*time.Millisecond, time.Minute)
+	if err := agent.Set(socket, "abc", []byte("xyz")); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if _, err := agent.Get(socket, "abc"); err == nil || err.Error() != "no cached key" {
+		t.Errorf("invalid error: %v", err)
+	}
+	_, socket = newServer(t, time.Minute, 300*time.Millisecond)
+	if err := agent.Set(socket, "abc", []byte("xyz")); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	for range 2 {
+		time.Sleep(100 * time.Millisecond)
+		if _, err := agent.Get(socket, "abc"); err != nil {
+			t.Errorf("invalid error: %v", err)
+		}
+	}
+	time.Sleep(200 * time.Millisecond)
+	if _, err := agent.Get(socket, "abc"); err == nil || err.Error() != "no cached key" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	s, socket := newServer(t, time.Minute, time.Minute)
+	if err := s.Close(); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := os.Stat(socket); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("socket not removed: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestDefaultSocket(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "xdg")
+	if s := agent.DefaultSocket(); s != filepath.Join("xdg", "lockbox", "agent.sock") {
+		t.Errorf("invalid socket: %s", s)
+	}
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	if s := agent.DefaultSocket(); !strings.HasSuffix(s, "agent.sock") || !strings.HasPrefix(s, os.TempDir()) {
+		t.Errorf("invalid socket: %s", s)
+	}
+}
@@ -0,0 +1,205 @@
+// Package agent handles serving cached keys over a unix socket
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/enckse/lockbox/internal/memory"
+)
+
+const purgeInterval = time.Second
+
+type (
+	cachedKey struct {
+		buffer   *memory.Buffer
+		created  time.Time
+		accessed time.Time
+	}
+	// Server is the agent process holding cached keys
+	Server struct {
+		socket   string
+		idle     time.Duration
+		absolute time.Duration
+		listener net.Listener
+		done     chan struct{}
+		mutex    sync.Mutex
+		keys     map[string]*cachedKey
+	}
+)
+
+// NewServer will create the agent socket (user-only) for serving keys
+func NewServer(socket string, idle, absolute time.Duration) (*Server, error) {
+	if idle <= 0 || absolute <= 0 {
+		return nil, errors.New("agent timeouts must be > 0")
+	}
+	dir := filepath.Dir(socket)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(dir, 0o700); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(socket); err == nil {
+		if conn, err := net.Dial("unix", socket); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("agent already running: %s", socket)
+		}
+		if err := os.Remove(socket); err != nil {
+			return nil, err
+		}
+	}
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(socket, 0o600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return &Server{
+		socket:   socket,
+		idle:     idle,
+		absolute: absolute,
+		listener: l,
+		done:     make(chan struct{}),
+		keys:     make(map[string]*cachedKey),
+	}, nil
+}
+
+// Run will serve requests until the server is closed
+func (s *Server) Run() error {
+	go s.expire()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return nil
+			default:
+				return err
+			}
+		}
+		go s.handle(conn)
+	}
+}
+
+// Close will stop the server and purge all keys
+func (s *Server) Close() error {
+	select {
+	case <-s.done:
+		return nil
+	default:
+	}
+	close(s.done)
+	s.purge("")
+	err := s.listener.Close()
+	os.Remove(s.socket)
+	return err
+}
+
+func (s *Server) expire() {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			s.mutex.Lock()
+			for id, key := range s.keys {
+				if s.expired(key, now) {
+					key.buffer.Destroy()
+					delete(s.keys, id)
+				}
+			}
+			s.mutex.Unlock()
+		}
+	}
+}
+
+func (s *Server) expired(key *cachedKey, now time.Time) bool {
+	return now.Sub(key.accessed) >= s.idle || now.Sub(key.created) >= s.absolute
+}
+
+func (s *Server) purge(id string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for k, key := range s.keys {
+		if id == "" || id == k {
+			key.buffer.Destroy()
+			delete(s.keys, k)
+		}
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(dialTimeout)); err != nil {
+		return
+	}
+	var req request
+	resp := response{}
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		resp.Error = fmt.Sprintf("invalid request: %v", err)
+	} else {
+		data, err := s.process(req)
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		resp.Data = data
+	}
+	json.NewEncoder(conn).Encode(resp)
+}
+
+func (s *Server) process(req request) ([]byte, error) {
+	switch req.Command {
+	case lockCommand:
+		s.purge("")
+		return nil, nil
+	case removeCommand:
+		s.purge(req.ID)
+		return nil, nil
+	}
+	if req.ID == "" {
+		return nil, errors.New("no key identifier given")
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now()
+	switch req.Command {
+	case getCommand:
+		key, ok := s.keys[req.ID]
+		if !ok {
+			return nil, errors.New("no cached key")
+		}
+		if s.expired(key, now) {
+			key.buffer.Destroy()
+			delete(s.keys, req.ID)
+			return nil, errors.New("no cached key")
+		}
+		key.accessed = now
+		return key.buffer.Bytes(), nil
+	case setCommand:
+		if len(req.Data) == 0 {
+			return nil, errors.New("no key data given")
+		}
+		buffer, err := memory.New(req.Data)
+		clear(req.Data)
+		if err != nil {
+			return nil, err
+		}
+		if existing, ok := s.keys[req.ID]; ok {
+			existing.buffer.Destroy()
+		}
+		s.keys[req.ID] = &cachedKey{buffer: buffer, created: now, accessed: now}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown agent command: %s", req.Command)
+}
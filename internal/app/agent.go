@@ -0,0 +1,84 @@
+// Package app handles key caching (agent and keyring)
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/enckse/lockbox/internal/agent"
+	"github.com/enckse/lockbox/internal/config"
+	"github.com/enckse/lockbox/internal/keyring"
+	"github.com/enckse/lockbox/internal/memory"
+)
+
+// Agent will run the key caching agent until interrupted
+func Agent(w io.Writer) error {
+	if err := memory.DisableCoreDumps(); err != nil {
+		return err
+	}
+	idle, err := config.EnvAgentIdleTimeout.Get()
+	if err != nil {
+		return err
+	}
+	absolute, err := config.EnvAgentTimeout.Get()
+	if err != nil {
+		return err
+	}
+	socket := config.AgentSocket()
+	server, err := agent.NewServer(socket, time.Duration(idle)*time.Second, time.Duration(absolute)*time.Second)
+	if err != nil {
+		return err
+	}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		<-sig
+		server.Close()
+	}()
+	fmt.Fprintf(w, "agent listening: %s\n", socket)
+	return server.Run()
+}
+
+// Lock will purge all keys cached by the agent and the store's key in the kernel keyring
+func Lock() error {
+	return purgeKeyCaches(true)
+}
+
+func purgeKeyCaches(all bool) error {
+	config.ForgetPromptedKeys()
+	id := config.EnvStore.Get()
+	socket := config.AgentSocket()
+	var err error
+	if all {
+		err = agent.Lock(socket)
+	} else {
+		err = agent.Remove(socket, id)
+	}
+	if err != nil && !errors.Is(err, agent.ErrNotRunning) {
+		return err
+	}
+	if id == "" {
+		return nil
+	}
+	err = keyring.Remove(keyring.Type(config.EnvKeyringType.Get()), id)
+	if err != nil && !errors.Is(err, keyring.ErrNotFound) && !errors.Is(err, keyring.ErrUnsupported) {
+		return err
+	}
+	return nil
+}
+
+func keyringStatus() string {
+	_, err := keyring.Get(keyring.Type(config.EnvKeyringType.Get()), config.EnvStore.Get())
+	switch {
+	case err == nil:
+		return "cached key present"
+	case errors.Is(err, keyring.ErrNotFound):
+		return "no cached key"
+	}
+	return fmt.Sprintf("error: %v", err)
+}
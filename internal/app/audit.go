@@ -0,0 +1,298 @@
+// Package app handles auditing entry secrets
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/enckse/lockbox/internal/app/commands"
+	"github.com/enckse/lockbox/internal/hibp"
+	"github.com/enckse/lockbox/internal/kdbx"
+	"github.com/enckse/lockbox/internal/strength"
+)
+
+const (
+	auditReused       = "reused"
+	auditWeak         = "weak"
+	auditPattern      = "pattern"
+	auditTitle        = "contains-title"
+	auditHost         = "contains-host"
+	auditEmpty        = "empty"
+	auditWhitespace   = "whitespace"
+	auditBreached     = "breached"
+	auditDefaultBits  = 50
+	auditMinUserInput = 3
+)
+
+type (
+	auditFinding struct {
+		Path   string `json:"path"`
+		Field  string `json:"field"`
+		Check  string `json:"check"`
+		Detail string `json:"detail,omitempty"`
+	}
+	auditReport struct {
+		Findings []auditFinding `json:"findings"`
+		Reused   [][]string     `json:"reused"`
+	}
+	auditArgs struct {
+		json       bool
+		exitCode   bool
+		minEntropy float64
+		filter     string
+		breachDB   string
+	}
+)
+
+var (
+	auditField = strings.ToLower(kdbx.PasswordField)
+	auditNotes = strings.ToLower(kdbx.NotesField)
+)
+
+// Audit will check entry secrets for reuse, weakness, and formatting problems
+func Audit(cmd CommandOptions) error {
+	args, err := readAuditArgs(cmd.Args())
+	if err != nil {
+		return err
+	}
+	var report auditReport
+	if args.breachDB == "" {
+		report, err = newAuditReport(cmd.Transaction(), args)
+	} else {
+		report, err = newBreachReport(cmd.Transaction(), args)
+	}
+	if err != nil {
+		return err
+	}
+	if err := cmd.Transaction().Record(commands.Audit, args.filter, ""); err != nil {
+		return err
+	}
+	w := cmd.Writer()
+	if args.json {
+		b, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\n", string(b))
+	} else {
+		for _, f := range report.Findings {
+			detail := ""
+			if f.Detail != "" {
+				detail = fmt.Sprintf(" (%s)", f.Detail)
+			}
+			fmt.Fprintf(w, "%s [%s]: %s%s\n", f.Path, f.Field, f.Check, detail)
+		}
+		for _, paths := range report.Reused {
+			fmt.Fprintf(w, "%s: %s\n", auditReused, strings.Join(paths, ", "))
+		}
+	}
+	if args.exitCode {
+		if count := len(report.Findings) + len(report.Reused); count > 0 {
+			return fmt.Errorf("audit found %d issue(s)", count)
+		}
+	}
+	return nil
+}
+
+func readAuditArgs(args []string) (auditArgs, error) {
+	breached := len(args) > 0 && args[0] == commands.AuditBreached
+	if breached {
+		args = args[1:]
+	}
+	set := flag.NewFlagSet("audit", flag.ExitOnError)
+	breachDB := set.String(commands.AuditFlags.DB, "", "sorted (by hash) SHA-1 breach database")
+	isJSON := set.Bool(commands.AuditFlags.JSON, false, "output results as JSON")
+	exitCode := set.Bool(commands.AuditFlags.ExitCode, false, "exit non-zero when issues are found")
+	minEntropy := set.Float64(commands.AuditFlags.MinEntropy, auditDefaultBits, "minimum estimated entropy (bits)")
+	if err := set.Parse(args); err != nil {
+		return auditArgs{}, err
+	}
+	var invalid error
+	set.Visit(func(f *flag.Flag) {
+		switch {
+		case invalid != nil:
+		case breached && f.Name == commands.AuditFlags.MinEntropy:
+			invalid = fmt.Errorf("-%s is not valid for %s", f.Name, commands.AuditBreached)
+		case !breached && f.Name == commands.AuditFlags.DB:
+			invalid = fmt.Errorf("-%s is only valid for %s", f.Name, commands.AuditBreached)
+		}
+	})
+	if invalid != nil {
+		return auditArgs{}, invalid
+	}
+	if *minEntropy <= 0 {
+		return auditArgs{}, errors.New("minimum entropy must be > 0")
+	}
+	parsed := auditArgs{json: *isJSON, exitCode: *exitCode, minEntropy: *minEntropy}
+	if breached {
+		if strings.TrimSpace(*breachDB) == "" {
+			return auditArgs{}, errors.New("breach database required")
+		}
+		parsed.breachDB = *breachDB
+	}
+	switch len(set.Args()) {
+	case 0:
+	case 1:
+		parsed.filter = set.Args()[0]
+	default:
+		return auditArgs{}, errors.New("invalid arguments")
+	}
+	return parsed, nil
+}
+
+func auditEntities(tx *kdbx.Transaction, filter string, cb func(kdbx.Entity) error) error {
+	hasFilter, selector := createFilter(filter)
+	e, err := tx.QueryCallback(kdbx.QueryOptions{Mode: kdbx.ListMode, Values: kdbx.SecretValue})
+	if err != nil {
+		return err
+	}
+	for item, err := range e {
+		if err != nil {
+			return err
+		}
+		if hasFilter {
+			ok, err := selector(filter, item.Path)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+		}
+		if err := cb(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newBreachReport(tx *kdbx.Transaction, args auditArgs) (auditReport, error) {
+	db, err := hibp.Open(args.breachDB)
+	if err != nil {
+		return auditReport{}, err
+	}
+	defer db.Close()
+	report := auditReport{Findings: []auditFinding{}, Reused: [][]string{}}
+	counts := make(map[string]int64)
+	err = auditEntities(tx, args.filter, func(item kdbx.Entity) error {
+		password, ok := item.Values[auditField]
+		if !ok || password == "" {
+			return nil
+		}
+		count, ok := counts[password]
+		if !ok {
+			found, err := db.Lookup(password)
+			if err != nil {
+				return err
+			}
+			count = found
+			counts[password] = found
+		}
+		if count > 0 {
+			report.Findings = append(report.Findings, auditFinding{Path: item.Path, Field: auditField, Check: auditBreached, Detail: fmt.Sprintf("%d", count)})
+		}
+		return nil
+	})
+	if err != nil {
+		return auditReport{}, err
+	}
+	return report, nil
+}
+
+func newAuditReport(tx *kdbx.Transaction, args auditArgs) (auditReport, error) {
+	report := auditReport{Findings: []auditFinding{}, Reused: [][]string{}}
+	passwords := make(map[string][]string)
+	var order []string
+	err := auditEntities(tx, args.filter, func(item kdbx.Entity) error {
+		add := func(field, check, detail string) {
+			report.Findings = append(report.Findings, auditFinding{Path: item.Path, Field: field, Check: check, Detail: detail})
+		}
+		fields := make([]string, 0, len(item.Values))
+		for field := range item.Values {
+			fields = append(fields, field)
+		}
+		slices.Sort(fields)
+		for _, field := range fields {
+			value := item.Values[field]
+			if strings.TrimSpace(value) == "" {
+				add(field, auditEmpty, "")
+				continue
+			}
+			if field != auditNotes && strings.TrimSpace(value) != value {
+				add(field, auditWhitespace, "leading/trailing whitespace")
+			}
+		}
+		password, ok := item.Values[auditField]
+		if !ok || strings.TrimSpace(password) == "" {
+			return nil
+		}
+		if _, ok := passwords[password]; !ok {
+			order = append(order, password)
+		}
+		passwords[password] = append(passwords[password], item.Path)
+		lower := strings.ToLower(password)
+		title := kdbx.Base(item.Path)
+		inputs := []string{title}
+		if len(title) >= auditMinUserInput && strings.Contains(lower, strings.ToLower(title)) {
+			add(auditField, auditTitle, title)
+		}
+		if host := auditHostname(item.Values[strings.ToLower(kdbx.URLField)]); host != "" {
+			inputs = append(inputs, host)
+			if label := strings.Split(host, ".")[0]; label != host {
+				inputs = append(inputs, label)
+			}
+			for _, check := range inputs[1:] {
+				if len(check) >= auditMinUserInput && strings.Contains(lower, check) {
+					add(auditField, auditHost, check)
+					break
+				}
+			}
+		}
+		result := strength.Estimate(password, inputs...)
+		if result.Entropy < args.minEntropy {
+			add(auditField, auditWeak, fmt.Sprintf("%.1f bits < %.1f", result.Entropy, args.minEntropy))
+		}
+		var patterns []string
+		for _, m := range result.Matches {
+			if m.Pattern == strength.UserInputPattern || slices.Contains(patterns, m.Pattern) {
+				continue
+			}
+			patterns = append(patterns, m.Pattern)
+		}
+		if len(patterns) > 0 {
+			add(auditField, auditPattern, strings.Join(patterns, ", "))
+		}
+		return nil
+	})
+	if err != nil {
+		return auditReport{}, err
+	}
+	for _, password := range order {
+		paths := passwords[password]
+		if len(paths) < 2 {
+			continue
+		}
+		report.Reused = append(report.Reused, paths)
+	}
+	return report, nil
+}
+
+func auditHostname(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ""
+	}
+	if !strings.Contains(value, "://") {
+		value = "https://" + value
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
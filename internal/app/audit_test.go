@@ -0,0 +1,115 @@
+package app_test
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/enckse/lockbox/internal/app"
+	"github.com/enckse/lockbox/internal/kdbx"
+)
+
+func newMockAudit(t *testing.T) *mockCommand {
+	setup(t)
+	fullSetup(t, true).Insert(kdbx.NewPath("audit", "github"), map[string]string{"password": "github123", "url": "https://www.github.com/login"})
+	fullSetup(t, true).Insert(kdbx.NewPath("audit", "host"), map[string]string{"password": "x7$Kq9!mZexample", "url": "example.com"})
+	fullSetup(t, true).Insert(kdbx.NewPath("audit", "strong1"), map[string]string{"password": "x7$Kq9!mZ2#pL4vR"})
+	fullSetup(t, true).Insert(kdbx.NewPath("audit", "strong2"), map[string]string{"password": "x7$Kq9!mZ2#pL4vR"})
+	fullSetup(t, true).Insert(kdbx.NewPath("audit", "padded"), map[string]string{"password": " Vq8#nL2$wR5!tY7& ", "notes": "text\n"})
+	fullSetup(t, true).Insert(kdbx.NewPath("other", "ok"), map[string]string{"password": "Vq8#nL2$wR5!tY7&pZ"})
+	return &mockCommand{t: t, confirmed: false, confirm: true}
+}
+
+func TestAudit(t *testing.T) {
+	m := newMockAudit(t)
+	m.args = []string{"a", "b"}
+	if err := app.Audit(m); err == nil || err.Error() != "invalid arguments" {
+		t.Errorf("invalid error: %v", err)
+	}
+	m.args = []string{"-min-entropy", "0"}
+	if err := app.Audit(m); err == nil || err.Error() != "minimum entropy must be > 0" {
+		t.Errorf("invalid error: %v", err)
+	}
+	m.args = []string{"-db", "pwned.txt"}
+	if err := app.Audit(m); err == nil || err.Error() != "-db is only valid for breached" {
+		t.Errorf("invalid error: %v", err)
+	}
+	m.args = []string{}
+	if err := app.Audit(m); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	expect := `audit/github [password]: contains-title (github)
+audit/github [password]: contains-host (github)
+audit/github [password]: weak (4.9 bits < 50.0)
+audit/github [password]: pattern (sequence)
+audit/host [password]: contains-host (example)
+audit/padded [password]: whitespace (leading/trailing whitespace)
+reused: audit/strong1, audit/strong2
+`
+	if m.buf.String() != expect {
+		t.Errorf("invalid audit: %s", m.buf.String())
+	}
+	m.buf.Reset()
+	m.args = []string{"-exit-code", "other/*"}
+	if err := app.Audit(m); err != nil || m.buf.String() != "" {
+		t.Errorf("invalid audit: %s %v", m.buf.String(), err)
+	}
+	m.args = []string{"-exit-code", "audit/strong*"}
+	if err := app.Audit(m); err == nil || err.Error() != "audit found 1 issue(s)" {
+		t.Errorf("invalid error: %v", err)
+	}
+	m.buf.Reset()
+	m.args = []string{"-json", "audit/host"}
+	if err := app.Audit(m); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	var report struct {
+		Findings []map[string]string `json:"findings"`
+		Reused   [][]string          `json:"reused"`
+	}
+	if err := json.Unmarshal(m.buf.Bytes(), &report); err != nil {
+		t.Errorf("invalid json: %v", err)
+	}
+	if len(report.Findings) != 1 || report.Findings[0]["check"] != "contains-host" || len(report.Reused) != 0 {
+		t.Errorf("invalid json: %v", report)
+	}
+	if strings.Contains(m.buf.String(), "x7$Kq9") {
+		t.Error("secret leaked")
+	}
+}
+
+func TestAuditBreached(t *testing.T) {
+	m := newMockAudit(t)
+	m.args = []string{"breached"}
+	if err := app.Audit(m); err == nil || err.Error() != "breach database required" {
+		t.Errorf("invalid error: %v", err)
+	}
+	m.args = []string{"breached", "-db", "pwned.txt", "-min-entropy", "60"}
+	if err := app.Audit(m); err == nil || err.Error() != "-min-entropy is not valid for breached" {
+		t.Errorf("invalid error: %v", err)
+	}
+	var lines []string
+	for idx, p := range []string{"github123", "x7$Kq9!mZ2#pL4vR", "other"} {
+		sum := sha1.Sum([]byte(p))
+		lines = append(lines, fmt.Sprintf("%X:%d", sum, idx+10))
+	}
+	slices.Sort(lines)
+	db := filepath.Join(t.TempDir(), "pwned.txt")
+	os.WriteFile(db, []byte(strings.Join(lines, "\n")), 0o600)
+	m.args = []string{"breached", "-db", db, "-exit-code"}
+	if err := app.Audit(m); err == nil || err.Error() != "audit found 3 issue(s)" {
+		t.Errorf("invalid error: %v", err)
+	}
+	expect := `audit/github [password]: breached (10)
+audit/strong1 [password]: breached (11)
+audit/strong2 [password]: breached (11)
+`
+	if m.buf.String() != expect {
+		t.Errorf("invalid audit: %s", m.buf.String())
+	}
+}
@@ -0,0 +1,73 @@
+// Package app handles the operations audit log
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/enckse/lockbox/internal/app/commands"
+	"github.com/enckse/lockbox/internal/auditlog"
+	"github.com/enckse/lockbox/internal/config"
+)
+
+const auditLogHashLength = 12
+
+// AuditLog will show or verify the operations audit log
+func AuditLog(cmd CommandOptions) error {
+	args := cmd.Args()
+	if len(args) == 0 {
+		return errors.New("invalid audit-log command")
+	}
+	log := config.EnvAuditLog.Get()
+	if log == "" {
+		return errors.New("audit log is not enabled")
+	}
+	w := cmd.Writer()
+	switch args[0] {
+	case commands.AuditLogShow:
+		if len(args) > 2 {
+			return errors.New("too many arguments (none or filter)")
+		}
+		var filter string
+		if len(args) == 2 {
+			filter = args[1]
+		}
+		hasFilter, selector := createFilter(filter)
+		records, err := auditlog.Read(log)
+		if err != nil {
+			return err
+		}
+		for _, r := range records {
+			if hasFilter {
+				ok, err := selector(filter, r.Path)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					continue
+				}
+			}
+			fields := []string{r.Time, r.Command}
+			if r.Path != "" {
+				fields = append(fields, r.Path)
+			}
+			if r.Hash != "" {
+				fields = append(fields, r.Hash[:min(len(r.Hash), auditLogHashLength)])
+			}
+			fmt.Fprintln(w, strings.Join(fields, " "))
+		}
+		return nil
+	case commands.AuditLogVerify:
+		if len(args) != 1 {
+			return errors.New("invalid audit-log command")
+		}
+		count, err := cmd.Transaction().VerifyLog()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "audit log verified: %d record(s)\n", count)
+		return nil
+	}
+	return fmt.Errorf("unknown audit-log command: %s", args[0])
+}
@@ -0,0 +1,77 @@
+package app_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/enckse/lockbox/internal/app"
+	"github.com/enckse/lockbox/internal/config/store"
+)
+
+func TestAuditLog(t *testing.T) {
+	defer store.Clear()
+	m := newMockCommand(t)
+	m.args = []string{"show"}
+	if err := app.AuditLog(m); err == nil || err.Error() != "audit log is not enabled" {
+		t.Errorf("invalid error: %v", err)
+	}
+	file := filepath.Join(t.TempDir(), "audit.log")
+	store.SetString("LOCKBOX_AUDIT_LOG", file)
+	m.args = []string{}
+	if err := app.AuditLog(m); err == nil || err.Error() != "invalid audit-log command" {
+		t.Errorf("invalid error: %v", err)
+	}
+	m.args = []string{"other"}
+	if err := app.AuditLog(m); err == nil || err.Error() != "unknown audit-log command: other" {
+		t.Errorf("invalid error: %v", err)
+	}
+	m.args = []string{"test/test2/test1/password"}
+	if err := app.ShowClip(m, true); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	m.args = []string{"test/test3/test1/notes"}
+	if err := app.ShowClip(m, true); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	m.args = []string{"test/test4/test5", "test/test4/test6"}
+	if err := app.Move(m); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	store.SetTable("LOCKBOX_ROTATION_POLICIES", map[string]string{"prod": "90d"})
+	m.args = []string{"test/*"}
+	if err := app.Stale(m); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := app.Shard(&m.buf, []string{"split"}, nil); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	m.buf.Reset()
+	m.args = []string{"show"}
+	if err := app.AuditLog(m); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(m.buf.String()), "\n")
+	if len(lines) != 5 || !strings.HasSuffix(lines[2], " mv test/test4/test5 -> test/test4/test6") || !strings.HasSuffix(lines[3], " stale test/*") || !strings.HasSuffix(lines[4], " shard split") {
+		t.Errorf("invalid log: %s", m.buf.String())
+	}
+	if fields := strings.Fields(lines[0]); len(fields) != 4 || fields[1] != "show" || fields[2] != "test/test2/test1/password" || len(fields[3]) != 12 {
+		t.Errorf("invalid log: %s", m.buf.String())
+	}
+	m.buf.Reset()
+	m.args = []string{"show", "test/test3"}
+	if err := app.AuditLog(m); err != nil || !strings.Contains(m.buf.String(), " show test/test3/test1/notes ") || strings.Count(m.buf.String(), "\n") != 1 {
+		t.Errorf("invalid log: %s %v", m.buf.String(), err)
+	}
+	m.buf.Reset()
+	m.args = []string{"verify"}
+	if err := app.AuditLog(m); err != nil || m.buf.String() != "audit log verified: 5 record(s)\n" {
+		t.Errorf("invalid verify: %s %v", m.buf.String(), err)
+	}
+	b, _ := os.ReadFile(file)
+	os.WriteFile(file, []byte(strings.Replace(string(b), "test/test3/test1/notes", "test/test3/test2/notes", 1)), 0o600)
+	if err := app.AuditLog(m); err == nil || err.Error() != "audit log tampered at record 2" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
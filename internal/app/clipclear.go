@@ -0,0 +1,69 @@
+// Package app handles clearing the clipboard
+package app
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/enckse/lockbox/internal/app/commands"
+	"github.com/enckse/lockbox/internal/memory"
+	"github.com/enckse/lockbox/internal/platform"
+)
+
+// ClipClear will wait (checking the clipboard) and clear the clipboard if it still holds the copied value
+func ClipClear(r io.Reader) error {
+	if err := memory.DisableCoreDumps(); err != nil {
+		return err
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	value, err := memory.New(b)
+	clear(b)
+	if err != nil {
+		return err
+	}
+	defer value.Destroy()
+	if value.Len() == 0 {
+		return nil
+	}
+	loader := platform.DefaultClipboardLoader{}
+	clipboard, err := platform.NewClipboard(loader, "")
+	if err != nil {
+		return err
+	}
+	paste, err := platform.NewClipboardPaste(loader)
+	if err != nil {
+		return err
+	}
+	for range clipboard.MaxTime {
+		time.Sleep(time.Second)
+		current, err := paste.Read()
+		if err != nil {
+			return err
+		}
+		if !value.Equal(current) {
+			return nil
+		}
+	}
+	return clipboard.CopyTo("")
+}
+
+// newClipboard will get the system clipboard, copied values are cleared by the clip-clear command
+func newClipboard() (platform.Clipboard, error) {
+	return platform.NewClipboard(platform.DefaultClipboardLoader{}, commands.ClipClear)
+}
+
+func clipTo(w io.Writer, clipboard platform.Clipboard, value string) error {
+	if err := clipboard.CopyTo(value); err != nil {
+		return err
+	}
+	if clipboard.MaxTime > 0 {
+		fmt.Fprintf(w, "clipboard will clear in %d seconds\n", clipboard.MaxTime)
+	} else if notice := clipboard.Notice(); notice != "" {
+		fmt.Fprintln(w, notice)
+	}
+	return nil
+}
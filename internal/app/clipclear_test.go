@@ -0,0 +1,60 @@
+package app_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/enckse/lockbox/internal/app"
+	"github.com/enckse/lockbox/internal/config/store"
+	"github.com/enckse/lockbox/internal/platform"
+)
+
+func TestClipClear(t *testing.T) {
+	store.Clear()
+	defer store.Clear()
+	cleared := filepath.Join(t.TempDir(), "cleared")
+	store.SetArray("LOCKBOX_CLIP_COPY", []string{"/bin/sh", "-c", "cat > " + cleared})
+	store.SetArray("LOCKBOX_CLIP_PASTE", []string{"/bin/echo", "other"})
+	store.SetInt64("LOCKBOX_CLIP_TIMEOUT", 1)
+	if err := app.ClipClear(strings.NewReader("value")); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if platform.PathExists(cleared) {
+		t.Error("changed clipboard should not be cleared")
+	}
+	store.SetArray("LOCKBOX_CLIP_PASTE", []string{"/bin/echo", "value"})
+	if err := app.ClipClear(strings.NewReader("value")); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if b, err := os.ReadFile(cleared); err != nil || len(b) != 0 {
+		t.Errorf("clipboard not cleared: %v", err)
+	}
+	os.Remove(cleared)
+	if err := app.ClipClear(strings.NewReader("")); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if platform.PathExists(cleared) {
+		t.Error("nothing to clear")
+	}
+	store.SetArray("LOCKBOX_CLIP_PASTE", []string{"/bin/sh", "-c", "exit 1"})
+	if err := app.ClipClear(strings.NewReader("value")); err == nil || err.Error() != "exit status 1" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestClipNotice(t *testing.T) {
+	defer store.Clear()
+	m := newMockCommand(t)
+	copied := filepath.Join(t.TempDir(), "copied")
+	store.SetArray("LOCKBOX_CLIP_COPY", []string{"/bin/sh", "-c", "cat > " + copied})
+	store.SetInt64("LOCKBOX_CLIP_TIMEOUT", 0)
+	m.args = []string{"test/test2/test1/password"}
+	if err := app.ShowClip(m, false); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if b, err := os.ReadFile(copied); err != nil || string(b) != "pass" || m.buf.String() != "" {
+		t.Errorf("invalid clip: %s %s %v", string(b), m.buf.String(), err)
+	}
+}
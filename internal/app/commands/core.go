@@ -22,6 +22,8 @@ const (
 	Move = "mv"
 	// Show will show the value in an entry
 	Show = "show"
+	// QR will show the value in an entry as a QR code
+	QR = "qr"
 	// Version displays version information
 	Version = "version"
 	// Help shows usage
@@ -48,6 +50,16 @@ const (
 	Completions = "completions"
 	// ReKey will rekey the underlying database
 	ReKey = "rekey"
+	// TOTPResync will resync a HOTP counter from a code
+	TOTPResync = "resync"
+	// TOTPImport will import an authenticator migration export
+	TOTPImport = "import"
+	// TOTPVerify will verify a TOTP code (within a number of windows)
+	TOTPVerify = "verify"
+	// TOTPWatch will show a refreshing table of TOTP tokens
+	TOTPWatch = "watch"
+	// TOTPQR will show the TOTP url as a QR code
+	TOTPQR = QR
 	// TOTPShow is for showing the TOTP token
 	TOTPShow = Show
 	// JSON handles JSON outputs
@@ -68,6 +80,36 @@ const (
 	Health = "health"
 	// Fields will display groups+possible/allowed fields
 	Fields = "fields"
+	// Agent runs the key caching agent
+	Agent = "agent"
+	// Lock purges all keys cached by the agent
+	Lock = "lock"
+	// KeyFile handles keyfile generation/verification
+	KeyFile = "keyfile"
+	// KeyFileNew will generate a new keyfile
+	KeyFileNew = "new"
+	// KeyFileVerify will verify an existing keyfile
+	KeyFileVerify = "verify"
+	// AuditLog will show/verify the operations audit log
+	AuditLog = "audit-log"
+	// AuditLogShow will show the audit log records
+	AuditLogShow = "show"
+	// AuditLogVerify will verify the audit log chain
+	AuditLogVerify = "verify"
+	// ClipClear is the (background) command that clears the clipboard
+	ClipClear = "clip-clear"
+	// Stale will list entries overdue for rotation
+	Stale = "stale"
+	// Audit will check entry secrets for weaknesses
+	Audit = "audit"
+	// AuditBreached will check entry secrets against an offline breach database
+	AuditBreached = "breached"
+	// Shard handles shamir sharding of the database password
+	Shard = "shard"
+	// ShardSplit will split the password into shards
+	ShardSplit = "split"
+	// ShardCombine will reconstruct the password from shards
+	ShardCombine = "combine"
 )
 
 var (
@@ -75,9 +117,45 @@ var (
 	CompletionTypes = []string{CompletionsBash, CompletionsZsh}
 	// ReKeyFlags are the flags used for re-keying
 	ReKeyFlags = struct {
-		KeyFile string
-		NoKey   string
-	}{"keyfile", "nokey"}
+		KeyFile        string
+		KeyFileCommand string
+		KeyFileFD      string
+		NoKey          string
+	}{"keyfile", "keyfile-command", "keyfile-fd", "nokey"}
+	// AuditFlags are the flags used for auditing
+	AuditFlags = struct {
+		JSON       string
+		ExitCode   string
+		MinEntropy string
+		DB         string
+	}{"json", "exit-code", "min-entropy", "db"}
+	// InsertFlags are the flags used for inserting
+	InsertFlags = struct {
+		Generate string
+		Override string
+		FromQR   string
+	}{"generate", "override", "from-qr"}
+	// MoveFlags are the flags used for moving
+	MoveFlags = struct {
+		Override string
+	}{"override"}
+	// ClipFlags are the flags used for clipping
+	ClipFlags = struct {
+		Sequence string
+	}{"sequence"}
+	// QRFlags are the flags used for QR codes
+	QRFlags = struct {
+		PNG string
+	}{"png"}
+	// TOTPImportFlags are the flags used for importing totp migrations
+	TOTPImportFlags = struct {
+		DryRun string
+	}{"dry-run"}
+	// ShardFlags are the flags used for splitting shards
+	ShardFlags = struct {
+		Shares    string
+		Threshold string
+	}{"shares", "threshold"}
 	// ReadOnly are readonly commands (they don't work in readonly mode)
 	ReadOnly = []string{Insert, Move, ReKey, Remove, Unset}
 )
@@ -99,6 +177,8 @@ func AllowedInReadOnly(cmds ...string) []string {
 
 // ReKeyArgs is the base definition of re-keying args
 type ReKeyArgs struct {
-	KeyFile string
-	NoKey   bool
+	KeyFile        string
+	KeyFileCommand []string
+	KeyFileFD      string
+	NoKey          bool
 }
@@ -24,6 +24,7 @@ type (
 		UnsetCommand        string
 		ClipCommand         string
 		ShowCommand         string
+		QRCommand           string
 		MultiLineCommand    string
 		MoveCommand         string
 		TOTPCommand         string
@@ -65,6 +66,7 @@ func Generate(completionType, exe string) ([]string, error) {
 		TOTPListCommand:     commands.TOTPList,
 		ClipCommand:         commands.Clip,
 		ShowCommand:         commands.Show,
+		QRCommand:           commands.QR,
 		JSONCommand:         commands.JSON,
 		HelpCommand:         commands.Help,
 		HelpAdvancedCommand: commands.HelpAdvanced,
@@ -78,7 +80,7 @@ func Generate(completionType, exe string) ([]string, error) {
 		DoFields:            fmt.Sprintf("%s %s", exe, commands.Fields),
 	}
 
-	c.Options = commands.AllowedInReadOnly(commands.Help, commands.List, commands.Show, commands.Version, commands.JSON, commands.Groups, commands.Move, commands.Remove, commands.Insert, commands.Unset)
+	c.Options = commands.AllowedInReadOnly(commands.Agent, commands.Audit, commands.KeyFile, commands.Lock, commands.Help, commands.List, commands.Show, commands.QR, commands.Version, commands.JSON, commands.Groups, commands.Move, commands.Remove, commands.Insert, commands.Unset, commands.Shard, commands.Stale, commands.AuditLog)
 
 	canClip := config.EnvFeatureClip.Get()
 	if canClip {
@@ -86,10 +88,13 @@ func Generate(completionType, exe string) ([]string, error) {
 	}
 	if config.EnvFeatureTOTP.Get() {
 		c.Options = append(c.Options, commands.TOTP)
-		c.TOTPSubCommands = []string{commands.TOTPMinimal, commands.TOTPOnce, commands.TOTPShow, commands.TOTPURL, commands.TOTPSeed}
+		c.TOTPSubCommands = []string{commands.TOTPMinimal, commands.TOTPOnce, commands.TOTPShow, commands.TOTPURL, commands.TOTPSeed, commands.TOTPWatch, commands.TOTPQR, commands.TOTPVerify}
 		if canClip {
 			c.TOTPSubCommands = append(c.TOTPSubCommands, commands.TOTPClip)
 		}
+		if !config.EnvReadOnly.Get() {
+			c.TOTPSubCommands = append(c.TOTPSubCommands, commands.TOTPResync, commands.TOTPImport)
+		}
 	}
 	sort.Strings(c.Options)
 	sort.Strings(c.TOTPSubCommands)
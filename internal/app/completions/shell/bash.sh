@@ -30,7 +30,7 @@ _{{ $.Executable }}() {
         "{{ $.InsertCommand }}")
           opts="$opts $({{ $.DoFields }})"
           ;;
-        "{{ $.UnsetCommand }}" | "{{ $.ShowCommand }}" | "{{ $.JSONCommand }}" | "{{ $.ClipCommand }}")
+        "{{ $.UnsetCommand }}" | "{{ $.ShowCommand }}" | "{{ $.QRCommand }}" | "{{ $.JSONCommand }}" | "{{ $.ClipCommand }}")
           opts=$({{ $.DoList }})
           ;;
         "{{ $.TOTPCommand }}")
@@ -42,7 +42,7 @@ _{{ $.Executable }}() {
             compadd "$@" $({{ $.DoFields }})
           fi
         ;;
-        "{{ $.UnsetCommand }}" | "{{ $.ShowCommand }}" | "{{ $.JSONCommand }}" | "{{ $.ClipCommand }}")
+        "{{ $.UnsetCommand }}" | "{{ $.ShowCommand }}" | "{{ $.QRCommand }}" | "{{ $.JSONCommand }}" | "{{ $.ClipCommand }}")
           if [ "$len" -eq 3 ]; then
             compadd "$@" $({{ $.DoList }})
           fi
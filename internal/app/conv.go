@@ -8,6 +8,7 @@ import (
 	"io"
 	"strings"
 
+	"github.com/enckse/lockbox/internal/app/commands"
 	"github.com/enckse/lockbox/internal/kdbx"
 )
 
@@ -70,6 +71,9 @@ func serialize(w io.Writer, tx *kdbx.Transaction, isJSON bool, filter string) er
 		trimmed = strings.TrimSuffix(trimmed, "}")
 		if isJSON {
 			fmt.Fprintf(w, "  %s", strings.TrimSpace(trimmed))
+			if err := tx.Record(commands.JSON, item.Path, ""); err != nil {
+				return err
+			}
 		} else {
 			for line := range strings.SplitSeq(trimmed, "\n") {
 				if strings.TrimSpace(line) == "" {
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/enckse/lockbox/internal/app/totp"
 	"github.com/enckse/lockbox/internal/config"
 	"github.com/enckse/lockbox/internal/platform"
 )
@@ -28,23 +29,37 @@ func Health(cmd CommandOptions) error {
 	w := cmd.Writer()
 	rawReport(w, "item", "status")
 	rawReport(w, "---", "---")
+	if config.EnvPasswordMode.Get() == string(config.KeyringKeyMode) {
+		rawReport(w, "keyring", keyringStatus())
+	}
 	key, err := config.NewKey(config.DefaultKeyMode)
 	if err == nil {
 		_, err = key.Read()
 	}
 	report(w, "key", err)
 	err = nil
-	file := config.EnvKeyFile.Get()
-	if file != "" {
-		err = errors.New("key file set, does not exist")
+	if config.EnvKeyFileMode.Get() == string(config.FileKeyFileMode) {
+		file := config.EnvKeyFile.Get()
+		if file != "" {
+			err = errors.New("key file set, does not exist")
 
-		if platform.PathExists(file) {
-			err = nil
+			if platform.PathExists(file) {
+				err = nil
+			}
+		}
+	} else {
+		var keyFile config.KeyFile
+		keyFile, err = config.NewKeyFile()
+		if err == nil {
+			_, err = keyFile.Read()
 		}
 	}
 	report(w, "keyfile", err)
-	_, err = platform.NewClipboard(platform.DefaultClipboardLoader{})
+	clipboard, err := newClipboard()
 	report(w, "clipboard", err)
+	if err == nil {
+		rawReport(w, "clip profile", clipboard.Profile())
+	}
 	store := config.EnvStore.Get()
 	err = errors.New("store not set")
 	if store != "" {
@@ -54,5 +69,13 @@ func Health(cmd CommandOptions) error {
 		}
 	}
 	report(w, "store", err)
+	if config.EnvFeatureTOTP.Get() {
+		offset, err := totp.ClockOffset()
+		if err == nil {
+			rawReport(w, "totp offset", offset.String())
+		} else {
+			report(w, "totp offset", err)
+		}
+	}
 	return nil
 }
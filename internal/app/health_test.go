@@ -1,6 +1,7 @@
 package app_test
 
 import (
+	"runtime"
 	"strings"
 	"testing"
 
@@ -83,7 +84,45 @@ func TestHealth(t *testing.T) {
 		t.Errorf("invalid error: %v", err)
 	}
 	s = m.buf.String()
-	if strings.Count(s, "ok") != 4 {
+	if strings.Count(s, "ok") != 4 || !strings.Contains(s, "totp offset     0s\n") {
+		t.Errorf("invalid health: %s", s)
+	}
+	m.buf.Reset()
+	store.SetString("LOCKBOX_TOTP_CLOCK_OFFSET", "x")
+	if err := app.Health(m); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if s := m.buf.String(); !strings.Contains(s, "totp offset     error: invalid totp clock offset") {
+		t.Errorf("invalid health: %s", s)
+	}
+}
+
+func TestHealthKeyring(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		return
+	}
+	m := newMockCommand(t)
+	store.SetString("LOCKBOX_CREDENTIALS_PASSWORD_MODE", "keyring")
+	store.SetString("LOCKBOX_KEYRING_TYPE", "session")
+	store.SetArray("LOCKBOX_CREDENTIALS_PASSWORD", []string{"/bin/echo", "test"})
+	defer app.Lock()
+	for _, expect := range []string{"no cached key", "cached key present"} {
+		m.buf.Reset()
+		if err := app.Health(m); err != nil {
+			t.Errorf("invalid error: %v", err)
+		}
+		if s := m.buf.String(); !strings.Contains(s, "keyring") || !strings.Contains(s, expect) {
+			t.Errorf("invalid health: %s", s)
+		}
+	}
+	if err := app.Lock(); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	m.buf.Reset()
+	if err := app.Health(m); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if s := m.buf.String(); !strings.Contains(s, "no cached key") {
 		t.Errorf("invalid health: %s", s)
 	}
 }
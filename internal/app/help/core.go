@@ -38,14 +38,66 @@ type (
 		HelpConfigCommand  string
 		NoColor            string
 		ReadOnlyCommands   string
-		Config             struct {
+		AgentCommand       string
+		LockCommand        string
+		HealthCommand      string
+		KeyFileCommand     string
+		StaleCommand       string
+		AuditLogCommand    string
+		TOTPResyncCommand  string
+		TOTPVerifyCommand  string
+		TOTPWatchCommand   string
+		QR                 struct {
+			Command string
+			TOTP    string
+			PNG     string
+			Insert  string
+		}
+		TOTPImport struct {
+			Command string
+			DryRun  string
+		}
+		Config struct {
 			Env  string
 			Home string
 			XDG  string
 		}
 		ReKey struct {
-			KeyFile string
-			NoKey   string
+			KeyFile        string
+			KeyFileCommand string
+			KeyFileFD      string
+			NoKey          string
+		}
+		Agent struct {
+			Mode string
+		}
+		Keyring struct {
+			Mode string
+		}
+		Audit struct {
+			Command    string
+			JSON       string
+			ExitCode   string
+			MinEntropy string
+			Breached   string
+			DB         string
+		}
+		Clip struct {
+			Command  string
+			Sequence string
+		}
+		Policy struct {
+			Command  string
+			Generate string
+			Override string
+			Move     string
+		}
+		Shards struct {
+			Mode      string
+			Split     string
+			Combine   string
+			Shares    string
+			Threshold string
 		}
 		Database struct {
 			Fields   string
@@ -78,6 +130,11 @@ func Usage(verbose bool, exe string) ([]string, error) {
 		isGroup  = "group"
 	)
 	var results []string
+	results = append(results, command(commands.Agent, "", "run the key caching agent"))
+	results = append(results, command(commands.Audit, isFilter, "audit entry secrets (reuse/strength)"))
+	results = append(results, subCommand(commands.Audit, commands.AuditBreached, isFilter, "check secrets against a breach file"))
+	results = append(results, subCommand(commands.AuditLog, commands.AuditLogShow, isFilter, "show the operations log"))
+	results = append(results, subCommand(commands.AuditLog, commands.AuditLogVerify, "", "verify the operations log"))
 	results = append(results, command(commands.Clip, isEntry, "copy the entry's value into the clipboard"))
 	results = append(results, command(commands.Completions, "<shell>", "generate completions via auto-detection"))
 	for _, c := range commands.CompletionTypes {
@@ -94,18 +151,32 @@ func Usage(verbose bool, exe string) ([]string, error) {
 	results = append(results, command(commands.Remove, isGroup, "remove an entry from the store"))
 	results = append(results, command(commands.Health, "", "display configuration health"))
 	results = append(results, command(commands.JSON, isFilter, "display detailed information"))
+	results = append(results, command(commands.KeyFile, "<command>", "keyfile generation/verification"))
+	results = append(results, subCommand(commands.KeyFile, commands.KeyFileNew, "<path>", "generate a new (xml v2.0) keyfile"))
+	results = append(results, subCommand(commands.KeyFile, commands.KeyFileVerify, "<path>", "verify a keyfile's format and integrity"))
 	results = append(results, command(commands.List, isFilter, "list entries"))
+	results = append(results, command(commands.Lock, "", "purge cached keys (agent/keyring)"))
 	results = append(results, command(commands.Groups, isFilter, "list groups"))
 	results = append(results, command(commands.Fields, isFilter, "list groups with all allowed field names"))
+	results = append(results, command(commands.Shard, "<command>", "shamir sharding of the database password"))
+	results = append(results, subCommand(commands.Shard, commands.ShardCombine, "", "reconstruct the password from shards"))
+	results = append(results, subCommand(commands.Shard, commands.ShardSplit, "", "split the password into shards"))
 	results = append(results, command(commands.Show, isEntry, "show the entry's value"))
+	results = append(results, command(commands.QR, isEntry, "show the entry's value as a QR code"))
+	results = append(results, command(commands.Stale, isFilter, "list entries overdue for rotation"))
 	results = append(results, command(commands.TOTP, "<command>", "display an updating totp generated code"))
 	results = append(results, subCommand(commands.TOTP, commands.TOTPClip, isEntry, "copy totp code to clipboard"))
+	results = append(results, subCommand(commands.TOTP, commands.TOTPImport, "group url", "import an authenticator migration url"))
 	results = append(results, subCommand(commands.TOTP, commands.TOTPList, isFilter, "list entries with totp settings"))
 	results = append(results, subCommand(commands.TOTP, commands.TOTPOnce, isEntry, "display the first generated code"))
 	results = append(results, subCommand(commands.TOTP, commands.TOTPMinimal, isEntry, "display one generated code (no details)"))
 	results = append(results, subCommand(commands.TOTP, commands.TOTPURL, isEntry, "display TOTP url information"))
+	results = append(results, subCommand(commands.TOTP, commands.TOTPQR, isEntry, "show the TOTP url as a QR code"))
+	results = append(results, subCommand(commands.TOTP, commands.TOTPResync, "entry code", "resync a HOTP counter from a code"))
+	results = append(results, subCommand(commands.TOTP, commands.TOTPVerify, "entry code", "verify a TOTP code (within windows)"))
 	results = append(results, subCommand(commands.TOTP, commands.TOTPSeed, isEntry, "show the TOTP seed (only)"))
 	results = append(results, subCommand(commands.TOTP, commands.TOTPShow, isEntry, "show the totp entry"))
+	results = append(results, subCommand(commands.TOTP, commands.TOTPWatch, isFilter, "show a table of totp codes"))
 	results = append(results, command(commands.Version, "", "display version information"))
 	sort.Strings(results)
 	usage := []string{fmt.Sprintf("%s usage:", exe)}
@@ -121,12 +192,48 @@ func Usage(verbose bool, exe string) ([]string, error) {
 			HelpConfigCommand:  commands.HelpConfig,
 			NoColor:            config.NoColorFlag,
 			ReadOnlyCommands:   strings.Join(commands.ReadOnly, ", "),
+			AgentCommand:       commands.Agent,
+			LockCommand:        commands.Lock,
+			HealthCommand:      commands.Health,
+			KeyFileCommand:     fmt.Sprintf("%s %s", commands.KeyFile, commands.KeyFileNew),
+			StaleCommand:       commands.Stale,
+			AuditLogCommand:    commands.AuditLog,
+			TOTPResyncCommand:  fmt.Sprintf("%s %s", commands.TOTP, commands.TOTPResync),
+			TOTPVerifyCommand:  fmt.Sprintf("%s %s", commands.TOTP, commands.TOTPVerify),
+			TOTPWatchCommand:   fmt.Sprintf("%s %s", commands.TOTP, commands.TOTPWatch),
 		}
 		document.Config.Env = config.ConfigEnv
 		document.Config.Home = config.ConfigHome
 		document.Config.XDG = config.ConfigXDG
 		document.ReKey.KeyFile = setDocFlag(commands.ReKeyFlags.KeyFile)
+		document.ReKey.KeyFileCommand = "-" + commands.ReKeyFlags.KeyFileCommand
+		document.ReKey.KeyFileFD = setDocFlag(commands.ReKeyFlags.KeyFileFD)
 		document.ReKey.NoKey = commands.ReKeyFlags.NoKey
+		document.Agent.Mode = string(config.AgentKeyMode)
+		document.Keyring.Mode = string(config.KeyringKeyMode)
+		document.Audit.Command = commands.Audit
+		document.Audit.JSON = "-" + commands.AuditFlags.JSON
+		document.Audit.ExitCode = "-" + commands.AuditFlags.ExitCode
+		document.Audit.MinEntropy = setDocFlag(commands.AuditFlags.MinEntropy)
+		document.Audit.Breached = fmt.Sprintf("%s %s", commands.Audit, commands.AuditBreached)
+		document.Audit.DB = setDocFlag(commands.AuditFlags.DB)
+		document.Clip.Command = commands.Clip
+		document.QR.Command = commands.QR
+		document.QR.TOTP = fmt.Sprintf("%s %s", commands.TOTP, commands.TOTPQR)
+		document.QR.PNG = "-" + commands.QRFlags.PNG
+		document.QR.Insert = fmt.Sprintf("%s -%s", commands.Insert, commands.InsertFlags.FromQR)
+		document.TOTPImport.Command = fmt.Sprintf("%s %s", commands.TOTP, commands.TOTPImport)
+		document.TOTPImport.DryRun = "-" + commands.TOTPImportFlags.DryRun
+		document.Clip.Sequence = "-" + commands.ClipFlags.Sequence
+		document.Policy.Command = commands.Insert
+		document.Policy.Generate = "-" + commands.InsertFlags.Generate
+		document.Policy.Override = "-" + commands.InsertFlags.Override
+		document.Policy.Move = fmt.Sprintf("%s -%s", commands.Move, commands.MoveFlags.Override)
+		document.Shards.Mode = string(config.ShardsKeyMode)
+		document.Shards.Split = fmt.Sprintf("%s %s", commands.Shard, commands.ShardSplit)
+		document.Shards.Combine = fmt.Sprintf("%s %s", commands.Shard, commands.ShardCombine)
+		document.Shards.Shares = setDocFlag(commands.ShardFlags.Shares)
+		document.Shards.Threshold = setDocFlag(commands.ShardFlags.Threshold)
 		document.Database.Fields = strings.Join(kdbx.AllFieldsLower, ", ")
 		var examples []string
 		for _, example := range []string{commands.Insert, commands.Show} {
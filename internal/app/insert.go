@@ -3,10 +3,12 @@ package app
 
 import (
 	"errors"
+	"flag"
 	"fmt"
 	"slices"
 	"strings"
 
+	"github.com/enckse/lockbox/internal/app/commands"
 	"github.com/enckse/lockbox/internal/app/totp"
 	"github.com/enckse/lockbox/internal/config"
 	"github.com/enckse/lockbox/internal/kdbx"
@@ -15,7 +17,14 @@ import (
 // Insert will execute an insert
 func Insert(cmd UserInputOptions) error {
 	t := cmd.Transaction()
-	args := cmd.Args()
+	set := flag.NewFlagSet("insert", flag.ExitOnError)
+	generate := set.Bool(commands.InsertFlags.Generate, false, "generate the password (within policy)")
+	override := set.Bool(commands.InsertFlags.Override, false, "insert (and record) a password that violates policy")
+	fromQR := set.String(commands.InsertFlags.FromQR, "", "read the (otp) value from a QR code image")
+	if err := set.Parse(cmd.Args()); err != nil {
+		return err
+	}
+	args := set.Args()
 	if len(args) != 1 {
 		return errors.New("invalid insert, no entry given")
 	}
@@ -27,6 +36,11 @@ func Insert(cmd UserInputOptions) error {
 		return fmt.Errorf("'%s' is not an allowed field name", base)
 	}
 
+	isOTP := strings.EqualFold(base, kdbx.OTPField)
+	if *fromQR != "" && !isOTP {
+		return errors.New("only otp entries can be read from a QR code")
+	}
+
 	dir := kdbx.Directory(entry)
 	existing, err := t.Get(dir, kdbx.SecretValue)
 	if err != nil {
@@ -42,22 +56,38 @@ func Insert(cmd UserInputOptions) error {
 			}
 		}
 	}
-	isPass := !strings.EqualFold(base, kdbx.URLField)
-	password, err := cmd.Input(!isPipe && !strings.EqualFold(base, kdbx.NotesField), isPass, base)
-	if err != nil {
-		return fmt.Errorf("invalid input: %w", err)
-	}
-	if !isPipe {
-		if isPass {
-			fmt.Fprintln(cmd.Writer())
+	var cleaned string
+	if *generate {
+		if !strings.EqualFold(base, kdbx.PasswordField) {
+			return errors.New("only passwords can be generated")
+		}
+		cleaned, err = kdbx.GeneratePassword(dir)
+		if err != nil {
+			return err
+		}
+	} else if *fromQR != "" {
+		cleaned, err = readOTPQR(*fromQR)
+		if err != nil {
+			return err
+		}
+	} else {
+		isPass := !strings.EqualFold(base, kdbx.URLField)
+		password, err := cmd.Input(!isPipe && !strings.EqualFold(base, kdbx.NotesField), isPass, base)
+		if err != nil {
+			return fmt.Errorf("invalid input: %w", err)
+		}
+		if !isPipe {
+			if isPass {
+				fmt.Fprintln(cmd.Writer())
+			}
 		}
+		cleaned = strings.TrimSpace(string(password))
 	}
 	vals := make(kdbx.EntityValues)
 	if existing != nil {
 		vals = existing.Values
 	}
-	cleaned := strings.TrimSpace(string(password))
-	if config.EnvTOTPCheckOnInsert.Get() && strings.EqualFold(base, kdbx.OTPField) {
+	if isOTP && (*fromQR != "" || config.EnvTOTPCheckOnInsert.Get()) {
 		generator, err := totp.New(cleaned)
 		if err != nil {
 			return err
@@ -67,8 +97,8 @@ func Insert(cmd UserInputOptions) error {
 		}
 	}
 	vals[base] = cleaned
-	if err := t.Insert(dir, vals); err != nil {
+	if err := t.InsertWithOptions(dir, vals, kdbx.InsertOptions{Generated: *generate, Override: *override}); err != nil {
 		return err
 	}
-	return nil
+	return t.Record(commands.Insert, entry, cleaned)
 }
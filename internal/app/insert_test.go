@@ -4,11 +4,15 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/enckse/lockbox/internal/app"
 	"github.com/enckse/lockbox/internal/config/store"
 	"github.com/enckse/lockbox/internal/kdbx"
+	"github.com/enckse/lockbox/internal/qr"
 )
 
 type (
@@ -193,3 +197,106 @@ func TestInsertTOTP(t *testing.T) {
 		t.Errorf("invalid error: %v", err)
 	}
 }
+
+func TestInsertPolicy(t *testing.T) {
+	defer store.Clear()
+	m := newMockInsert(t)
+	m.pipe = func() bool {
+		return true
+	}
+	m.input = func() ([]byte, error) {
+		return []byte("short"), nil
+	}
+	store.SetTable("LOCKBOX_POLICY_MIN_LENGTH", map[string]string{"test": "12"})
+	m.command.args = []string{"test/test2/test1/password"}
+	if err := app.Insert(m); err == nil || err.Error() != "password policy violation for test/test2/test1: minimum length 12 (have 5)" {
+		t.Errorf("invalid error: %v", err)
+	}
+	m.command.args = []string{"-override", "test/test2/test1/password"}
+	if err := app.Insert(m); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	m.command.args = []string{"-generate", "test/test2/test1/url"}
+	if err := app.Insert(m); err == nil || err.Error() != "only passwords can be generated" {
+		t.Errorf("invalid error: %v", err)
+	}
+	m.input = func() ([]byte, error) {
+		return nil, errors.New("no input for generated")
+	}
+	m.command.args = []string{"-generate", "test/test2/test1/password"}
+	if err := app.Insert(m); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	e, err := m.Transaction().Get("test/test2/test1", kdbx.SecretValue)
+	if err != nil || e == nil || len(e.Values["password"]) != 32 || e.PolicyOverride != "" {
+		t.Errorf("invalid entity: %v %v", e, err)
+	}
+}
+
+func writeQRImage(t *testing.T, value string) string {
+	c, err := qr.Encode([]byte(value))
+	if err != nil {
+		t.Fatalf("invalid error: %v", err)
+	}
+	file := filepath.Join(t.TempDir(), "qr.png")
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatalf("invalid error: %v", err)
+	}
+	defer f.Close()
+	if err := c.PNG(f, 3); err != nil {
+		t.Fatalf("invalid error: %v", err)
+	}
+	return file
+}
+
+func TestInsertFromQR(t *testing.T) {
+	defer store.Clear()
+	m := newMockInsert(t)
+	m.pipe = func() bool {
+		return true
+	}
+	m.input = func() ([]byte, error) {
+		return nil, errors.New("no input expected")
+	}
+	otp := "otpauth://totp/lb:test?secret=JBSWY3DPEHPK3PXP"
+	m.command.args = []string{"-from-qr", writeQRImage(t, otp), "test/test2/test1/password"}
+	if err := app.Insert(m); err == nil || err.Error() != "only otp entries can be read from a QR code" {
+		t.Errorf("invalid error: %v", err)
+	}
+	m.command.args = []string{"-from-qr", writeQRImage(t, "https://example.com"), "test/test2/test1/otp"}
+	if err := app.Insert(m); err == nil || err.Error() != "QR code is not an otpauth url" {
+		t.Errorf("invalid error: %v", err)
+	}
+	store.SetBool("LOCKBOX_TOTP_CHECK_ON_INSERT", false)
+	m.command.args = []string{"-from-qr", writeQRImage(t, "otpauth://totp/lb:test?secret=1"), "test/test2/test1/otp"}
+	if err := app.Insert(m); err == nil || err.Error() != "illegal base32 data at input byte 0" {
+		t.Errorf("invalid error: %v", err)
+	}
+	m.command.args = []string{"-from-qr", filepath.Join(t.TempDir(), "missing.png"), "test/test2/test1/otp"}
+	if err := app.Insert(m); err == nil || !strings.Contains(err.Error(), "no such file or directory") {
+		t.Errorf("invalid error: %v", err)
+	}
+	m.command.args = []string{"-from-qr", writeQRImage(t, otp), "test/test2/test1/otp"}
+	if err := app.Insert(m); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	e, err := m.Transaction().Get("test/test2/test1", kdbx.SecretValue)
+	if err != nil || e.Values["otp"] != otp || e.Values["password"] != "pass" {
+		t.Errorf("invalid entry: %v %v", e, err)
+	}
+	migration := "otpauth-migration://offline?data=Ci4KCkhlbGxvId6tvu8SEWFsaWNlQGV4YW1wbGUuY29tGgdFeGFtcGxlIAEoATACOAE%3D"
+	m.command.args = []string{"-from-qr", writeQRImage(t, migration), "test/test2/test3/otp"}
+	if err := app.Insert(m); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	e, err = m.Transaction().Get("test/test2/test3", kdbx.SecretValue)
+	if err != nil || e.Values["otp"] != "otpauth://totp/Example:alice@example.com?algorithm=SHA1&digits=6&issuer=Example&period=30&secret=JBSWY3DPEHPK3PXP" {
+		t.Errorf("invalid entry: %v %v", e, err)
+	}
+	migration = "otpauth-migration://offline?data=Ci4KCkhlbGxvId6tvu8SEWFsaWNlQGV4YW1wbGUuY29tGgdFeGFtcGxlIAEoATACCigKFDEyMzQ1Njc4OTAxMjM0NTY3ODkwEgp0ZXN0Mzp0b3RwIAEoATACEAE%3D"
+	m.command.args = []string{"-from-qr", writeQRImage(t, migration), "test/test2/test3/otp"}
+	if err := app.Insert(m); err == nil || err.Error() != "QR code holds 2 accounts, only one can be inserted (see totp import)" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+// Package app handles keyfile generation/verification
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/enckse/lockbox/internal/app/commands"
+	"github.com/enckse/lockbox/internal/kdbx"
+)
+
+// KeyFile will create or verify a KeePass keyfile
+func KeyFile(w io.Writer, args []string) error {
+	if len(args) != 2 {
+		return errors.New("invalid keyfile command")
+	}
+	path := args[1]
+	if strings.TrimSpace(path) == "" {
+		return errors.New("keyfile path required")
+	}
+	switch args[0] {
+	case commands.KeyFileNew:
+		return newKeyFile(w, path)
+	case commands.KeyFileVerify:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		format, err := kdbx.VerifyKeyFile(data)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s: %s\n", path, format)
+		return nil
+	}
+	return fmt.Errorf("unknown keyfile command: %s", args[0])
+}
+
+func newKeyFile(w io.Writer, path string) error {
+	data, err := kdbx.NewKeyFile()
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("refusing to overwrite existing file: %s", path)
+		}
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "keyfile created: %s\n", path)
+	return nil
+}
@@ -0,0 +1,38 @@
+package app_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/enckse/lockbox/internal/app"
+)
+
+func TestKeyFileCommand(t *testing.T) {
+	var buf bytes.Buffer
+	if err := app.KeyFile(&buf, []string{}); err == nil || err.Error() != "invalid keyfile command" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := app.KeyFile(&buf, []string{"xyz", "a"}); err == nil || err.Error() != "unknown keyfile command: xyz" {
+		t.Errorf("invalid error: %v", err)
+	}
+	file := filepath.Join(t.TempDir(), "test.key")
+	if err := app.KeyFile(&buf, []string{"new", file}); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	info, err := os.Stat(file)
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("invalid keyfile: %v %v", info, err)
+	}
+	if err := app.KeyFile(&buf, []string{"new", file}); err == nil || err.Error() != "refusing to overwrite existing file: "+file {
+		t.Errorf("invalid error: %v", err)
+	}
+	buf.Reset()
+	if err := app.KeyFile(&buf, []string{"verify", file}); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if buf.String() != file+": xml (v2.0)\n" {
+		t.Errorf("invalid output: %s", buf.String())
+	}
+}
@@ -3,8 +3,10 @@ package app
 
 import (
 	"errors"
+	"flag"
 	"fmt"
 
+	"github.com/enckse/lockbox/internal/app/commands"
 	"github.com/enckse/lockbox/internal/kdbx"
 )
 
@@ -19,7 +21,12 @@ type (
 
 // Move is the CLI command to move entries
 func Move(cmd CommandOptions) error {
-	args := cmd.Args()
+	set := flag.NewFlagSet("move", flag.ExitOnError)
+	override := set.Bool(commands.MoveFlags.Override, false, "move (and record) a password that violates policy at the destination")
+	if err := set.Parse(cmd.Args()); err != nil {
+		return err
+	}
+	args := set.Args()
 	if len(args) != 2 {
 		return errors.New("src/dst required for move")
 	}
@@ -68,7 +75,15 @@ func Move(cmd CommandOptions) error {
 			moving = append(moving, *req)
 		}
 	}
-	return t.Move(moving...)
+	if err := t.MoveWithOptions(kdbx.InsertOptions{Override: *override}, moving...); err != nil {
+		return err
+	}
+	for _, m := range moving {
+		if err := t.Record(commands.Move, fmt.Sprintf("%s -> %s", m.Source.Path, m.Destination), ""); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (r moveRequest) do(dryRun bool) (*kdbx.MoveRequest, error) {
@@ -3,9 +3,11 @@ package app_test
 import (
 	"bytes"
 	"io"
+	"strings"
 	"testing"
 
 	"github.com/enckse/lockbox/internal/app"
+	"github.com/enckse/lockbox/internal/config/store"
 	"github.com/enckse/lockbox/internal/kdbx"
 )
 
@@ -16,6 +18,7 @@ type (
 		buf       bytes.Buffer
 		confirmed bool
 		confirm   bool
+		tx        *kdbx.Transaction
 	}
 )
 
@@ -36,7 +39,10 @@ func (m *mockCommand) Confirm(string) bool {
 }
 
 func (m *mockCommand) Transaction() *kdbx.Transaction {
-	return fullSetup(m.t, true)
+	if m.tx == nil {
+		m.tx = fullSetup(m.t, true)
+	}
+	return m.tx
 }
 
 func (m *mockCommand) Args() []string {
@@ -81,3 +87,22 @@ func TestMove(t *testing.T) {
 		t.Errorf("invalid error: %v", err)
 	}
 }
+
+func TestMovePolicy(t *testing.T) {
+	store.Clear()
+	defer store.Clear()
+	m := newMockCommand(t)
+	store.SetTable("LOCKBOX_POLICY_MIN_LENGTH", map[string]string{"prod": "12"})
+	m.args = []string{"test/test2/test1", "prod/test1"}
+	if err := app.Move(m); err == nil || err.Error() != "password policy violation for prod/test1: minimum length 12 (have 4)" {
+		t.Errorf("invalid error: %v", err)
+	}
+	m.args = []string{"-override", "test/test2/test1", "prod/test1"}
+	if err := app.Move(m); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	e, err := fullSetup(t, true).Get(kdbx.NewPath("prod", "test1"), kdbx.SecretValue)
+	if err != nil || e == nil || !strings.HasSuffix(e.PolicyOverride, " minimum length 12 (have 4)") {
+		t.Errorf("invalid entity: %v %v", e, err)
+	}
+}
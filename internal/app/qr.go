@@ -0,0 +1,87 @@
+// Package app handles showing (and reading) entry values as QR codes
+package app
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/enckse/lockbox/internal/app/commands"
+	"github.com/enckse/lockbox/internal/app/totp"
+	"github.com/enckse/lockbox/internal/config"
+	"github.com/enckse/lockbox/internal/qr"
+)
+
+// pngScale is the pixels per module when writing QR codes as png images
+const pngScale = 8
+
+// QR will show an entry value as a QR code (or write it to a png)
+func QR(cmd CommandOptions) error {
+	set := flag.NewFlagSet("qr", flag.ExitOnError)
+	file := set.String(commands.QRFlags.PNG, "", "write the QR code to a png file")
+	if err := set.Parse(cmd.Args()); err != nil {
+		return err
+	}
+	args := set.Args()
+	if len(args) != 1 {
+		return errors.New("only one argument supported")
+	}
+	val, err := getEntity(args[0], cmd, commands.QR)
+	if err != nil {
+		return err
+	}
+	return writeQR(cmd.Writer(), val, *file)
+}
+
+func writeQR(w io.Writer, value, file string) error {
+	code, err := qr.Encode([]byte(value))
+	if err != nil {
+		return err
+	}
+	if file == "" {
+		fmt.Fprint(w, code.Terminal(config.CanColor()))
+		return nil
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return code.PNG(f, pngScale)
+}
+
+// readOTPQR reads an otpauth url from a QR code image (a migration export must hold one account)
+func readOTPQR(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	data, err := qr.Read(f)
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSpace(string(data))
+	u, err := url.Parse(value)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "otpauth":
+		return value, nil
+	case "otpauth-migration":
+		accounts, err := totp.ParseMigration(value)
+		if err != nil {
+			return "", err
+		}
+		if len(accounts) != 1 {
+			return "", fmt.Errorf("QR code holds %d accounts, only one can be inserted (see totp import)", len(accounts))
+		}
+		return accounts[0].URL, nil
+	}
+	return "", errors.New("QR code is not an otpauth url")
+}
@@ -0,0 +1,57 @@
+package app_test
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/enckse/lockbox/internal/app"
+	"github.com/enckse/lockbox/internal/config/store"
+)
+
+func TestQR(t *testing.T) {
+	defer store.Clear()
+	m := newMockCommand(t)
+	if err := app.QR(m); err == nil || err.Error() != "only one argument supported" {
+		t.Errorf("invalid error: %v", err)
+	}
+	m.args = []string{"test211/test2/test/password"}
+	if err := app.QR(m); err == nil || err.Error() != "entry does not exist" {
+		t.Errorf("invalid error: %v", err)
+	}
+	store.SetBool("LOCKBOX_FEATURE_COLOR", false)
+	m.args = []string{"test/test2/test1/password"}
+	if err := app.QR(m); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(m.buf.String(), "\n"), "\n")
+	if len(lines) != 15 || !strings.HasPrefix(lines[2], "████ ▄▄▄▄▄ █") {
+		t.Errorf("invalid qr: %s", m.buf.String())
+	}
+	store.SetBool("LOCKBOX_FEATURE_COLOR", true)
+	m.buf = bytes.Buffer{}
+	if err := app.QR(m); err != nil || !strings.HasPrefix(m.buf.String(), "\x1b[97;40m") {
+		t.Errorf("invalid qr: %s %v", m.buf.String(), err)
+	}
+	m.buf = bytes.Buffer{}
+	file := filepath.Join(t.TempDir(), "qr.png")
+	m.args = []string{"-png", file, "test/test2/test1/password"}
+	if err := app.QR(m); err != nil || m.buf.String() != "" {
+		t.Errorf("invalid qr: %s %v", m.buf.String(), err)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatalf("invalid error: %v", err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil || img.Bounds().Dx() != 232 {
+		t.Errorf("invalid png: %v", err)
+	}
+	if info, _ := os.Stat(file); info.Mode().Perm() != 0o600 {
+		t.Errorf("invalid mode: %v", info.Mode())
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/enckse/lockbox/internal/app/commands"
+	"github.com/enckse/lockbox/internal/config"
 )
 
 // ReKey handles entry rekeying
@@ -30,20 +31,66 @@ func ReKey(cmd UserInputOptions) error {
 		}
 		pass = string(p)
 	}
-	return cmd.Transaction().ReKey(pass, vars.KeyFile)
+	keyFile, err := readKeyFile(vars)
+	if err != nil {
+		return err
+	}
+	t := cmd.Transaction()
+	if err := t.ReKey(pass, keyFile); err != nil {
+		return err
+	}
+	if err := t.Record(commands.ReKey, "", ""); err != nil {
+		return err
+	}
+	// NOTE: any cached key is now stale
+	return purgeKeyCaches(false)
 }
 
 func readArgs(args []string) (commands.ReKeyArgs, error) {
 	set := flag.NewFlagSet("rekey", flag.ExitOnError)
 	keyFile := set.String(commands.ReKeyFlags.KeyFile, "", "new keyfile")
+	keyFileCommand := set.Bool(commands.ReKeyFlags.KeyFileCommand, false, "read the new keyfile from the command (remaining arguments)")
+	keyFileFD := set.String(commands.ReKeyFlags.KeyFileFD, "", "inherited file descriptor to read the new keyfile from")
 	noKey := set.Bool(commands.ReKeyFlags.NoKey, false, "disable password/key credential")
 	if err := set.Parse(args); err != nil {
 		return commands.ReKeyArgs{}, err
 	}
 	noPass := *noKey
-	file := *keyFile
-	if strings.TrimSpace(file) == "" && noPass {
+	vars := commands.ReKeyArgs{KeyFile: *keyFile, KeyFileFD: *keyFileFD, NoKey: noPass}
+	sources := 0
+	if *keyFileCommand {
+		vars.KeyFileCommand = set.Args()
+		sources++
+	} else if len(set.Args()) > 0 {
+		return commands.ReKeyArgs{}, errors.New("invalid arguments")
+	}
+	for _, source := range []string{vars.KeyFile, vars.KeyFileFD} {
+		if strings.TrimSpace(source) != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return commands.ReKeyArgs{}, errors.New("only one keyfile source can be passed for rekey")
+	}
+	if sources == 0 && noPass {
 		return commands.ReKeyArgs{}, errors.New("a key or keyfile must be passed for rekey")
 	}
-	return commands.ReKeyArgs{KeyFile: file, NoKey: noPass}, nil
+	return vars, nil
+}
+
+func readKeyFile(vars commands.ReKeyArgs) ([]byte, error) {
+	mode := config.FileKeyFileMode
+	source := []string{vars.KeyFile}
+	if vars.KeyFileCommand != nil {
+		mode = config.CommandKeyFileMode
+		source = vars.KeyFileCommand
+	} else if strings.TrimSpace(vars.KeyFileFD) != "" {
+		mode = config.FDKeyFileMode
+		source = []string{vars.KeyFileFD}
+	}
+	keyFile, err := config.NewKeyFileSource(mode, source...)
+	if err != nil {
+		return nil, err
+	}
+	return keyFile.Read()
 }
@@ -94,4 +94,28 @@ func TestReKeyFlags(t *testing.T) {
 	if err := app.ReKey(mock); err == nil || err.Error() != "no keyfile found on disk" {
 		t.Errorf("invalid error: %v", err)
 	}
+	mock.args = []string{"-keyfile", "blla", "-keyfile-fd", "3"}
+	if err := app.ReKey(mock); err == nil || err.Error() != "only one keyfile source can be passed for rekey" {
+		t.Errorf("invalid error: %v", err)
+	}
+	mock.args = []string{"-keyfile", "blla", "-keyfile-command", "/bin/echo", "newkey"}
+	if err := app.ReKey(mock); err == nil || err.Error() != "only one keyfile source can be passed for rekey" {
+		t.Errorf("invalid error: %v", err)
+	}
+	mock.args = []string{"-nokey", "/bin/echo", "newkey"}
+	if err := app.ReKey(mock); err == nil || err.Error() != "invalid arguments" {
+		t.Errorf("invalid error: %v", err)
+	}
+	mock.args = []string{"-nokey", "-keyfile-command"}
+	if err := app.ReKey(mock); err == nil || err.Error() != "keyfile MUST be set in command keyfile mode" {
+		t.Errorf("invalid error: %v", err)
+	}
+	mock.args = []string{"-nokey", "-keyfile-command", "/bin/sh", "-c", "exit"}
+	if err := app.ReKey(mock); err == nil || err.Error() != "keyfile is empty" {
+		t.Errorf("invalid error: %v", err)
+	}
+	mock.args = []string{"-nokey", "-keyfile-command", "--", "/bin/sh", "-c", "echo new key"}
+	if err := app.ReKey(mock); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
 }
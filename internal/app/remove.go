@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/enckse/lockbox/internal/app/commands"
 	"github.com/enckse/lockbox/internal/kdbx"
 )
 
@@ -40,6 +41,11 @@ func remove(t *kdbx.Transaction, w io.Writer, entry string, cmd CommandOptions)
 		if err := t.RemoveAll(existings); err != nil {
 			return fmt.Errorf("unable to remove: %w", err)
 		}
+		for _, e := range existings {
+			if err := t.Record(commands.Remove, e.Path, ""); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
@@ -0,0 +1,101 @@
+// Package app handles shamir sharding of the database password
+package app
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/enckse/lockbox/internal/app/commands"
+	"github.com/enckse/lockbox/internal/config"
+	"github.com/enckse/lockbox/internal/kdbx"
+	"github.com/enckse/lockbox/internal/platform"
+	"github.com/enckse/lockbox/internal/platform/tty"
+	"github.com/enckse/lockbox/internal/shamir"
+)
+
+// Shard will split the configured password into shards or combine shards back into it
+func Shard(w io.Writer, args []string, read func(string) (string, error)) error {
+	if len(args) == 0 {
+		return errors.New("invalid shard command")
+	}
+	switch args[0] {
+	case commands.ShardSplit:
+		return shardSplit(w, args[1:])
+	case commands.ShardCombine:
+		if len(args) != 1 {
+			return errors.New("invalid shard command")
+		}
+		b, err := config.ReadShards(read)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\n", string(b))
+		clear(b)
+		return nil
+	}
+	return fmt.Errorf("unknown shard command: %s", args[0])
+}
+
+func shardSplit(w io.Writer, args []string) error {
+	set := flag.NewFlagSet("shard", flag.ExitOnError)
+	shares := set.Int(commands.ShardFlags.Shares, 5, "number of shards to create")
+	threshold := set.Int(commands.ShardFlags.Threshold, 3, "number of shards required to reconstruct")
+	if err := set.Parse(args); err != nil {
+		return err
+	}
+	if len(set.Args()) > 0 {
+		return errors.New("invalid shard command")
+	}
+	key, err := config.NewKey(config.DefaultKeyMode)
+	if err != nil {
+		return err
+	}
+	pass, err := key.Read()
+	if err != nil {
+		return err
+	}
+	if pass == "" {
+		return errors.New("no password to shard")
+	}
+	split, err := shamir.Split([]byte(pass), *shares, *threshold)
+	if err != nil {
+		return err
+	}
+	if config.EnvAuditLog.Get() != "" {
+		t, err := kdbx.NewTransaction()
+		if err != nil {
+			return err
+		}
+		if err := t.Unlock(); err != nil {
+			return err
+		}
+		if err := t.Record(fmt.Sprintf("%s %s", commands.Shard, commands.ShardSplit), "", ""); err != nil {
+			return err
+		}
+	}
+	for _, s := range split {
+		fmt.Fprintf(w, "%s\n", s.String())
+	}
+	return nil
+}
+
+// ShardReader will read shards from stdin (when piped) or prompt on the terminal
+func ShardReader() func(string) (string, error) {
+	if !platform.IsInputFromPipe() {
+		return tty.ReadSecret
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	return func(string) (string, error) {
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return "", err
+			}
+			return "", errors.New("not enough shards given")
+		}
+		return scanner.Text(), nil
+	}
+}
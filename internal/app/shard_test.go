@@ -0,0 +1,57 @@
+package app_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/enckse/lockbox/internal/app"
+	"github.com/enckse/lockbox/internal/config/store"
+)
+
+func shardReader(shards []string) func(string) (string, error) {
+	return func(string) (string, error) {
+		if len(shards) == 0 {
+			return "", errors.New("not enough shards given")
+		}
+		s := shards[0]
+		shards = shards[1:]
+		return s, nil
+	}
+}
+
+func TestShard(t *testing.T) {
+	store.Clear()
+	defer store.Clear()
+	var buf bytes.Buffer
+	if err := app.Shard(&buf, []string{}, nil); err == nil || err.Error() != "invalid shard command" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := app.Shard(&buf, []string{"xyz"}, nil); err == nil || err.Error() != "unknown shard command: xyz" {
+		t.Errorf("invalid error: %v", err)
+	}
+	store.SetString("LOCKBOX_CREDENTIALS_PASSWORD_MODE", "plaintext")
+	store.SetArray("LOCKBOX_CREDENTIALS_PASSWORD", []string{"shardtest"})
+	if err := app.Shard(&buf, []string{"split", "-shares", "2", "-threshold", "3"}, nil); err == nil || err.Error() != "shares must be >= threshold" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := app.Shard(&buf, []string{"split", "--shares", "5", "--threshold", "3"}, nil); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	shards := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(shards) != 5 {
+		t.Errorf("invalid shards: %v", shards)
+	}
+	buf.Reset()
+	if err := app.Shard(&buf, []string{"combine"}, shardReader(shards[:2])); err == nil || err.Error() != "not enough shards given" {
+		t.Errorf("invalid error: %v", err)
+	}
+	buf.Reset()
+	if err := app.Shard(&buf, []string{"combine"}, shardReader([]string{shards[4], shards[1], shards[2]})); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if buf.String() != "shardtest\n" {
+		t.Errorf("invalid combine: %s", buf.String())
+	}
+}
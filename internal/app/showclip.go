@@ -2,16 +2,38 @@
 package app
 
 import (
+	"bufio"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
+	"github.com/enckse/lockbox/internal/app/commands"
+	"github.com/enckse/lockbox/internal/app/totp"
 	"github.com/enckse/lockbox/internal/kdbx"
 	"github.com/enckse/lockbox/internal/platform"
 )
 
+type clipStep struct {
+	field string
+	value string
+	otp   *totp.Generator
+}
+
 // ShowClip will handle showing/clipping an entry
 func ShowClip(cmd CommandOptions, isShow bool) error {
 	args := cmd.Args()
+	var sequence bool
+	if !isShow {
+		set := flag.NewFlagSet("clip", flag.ExitOnError)
+		set.BoolVar(&sequence, commands.ClipFlags.Sequence, false, "copy the url, password, and totp code in order")
+		if err := set.Parse(args); err != nil {
+			return err
+		}
+		args = set.Args()
+	}
 	if len(args) != 1 {
 		return errors.New("only one argument supported")
 	}
@@ -19,12 +41,19 @@ func ShowClip(cmd CommandOptions, isShow bool) error {
 	clipboard := platform.Clipboard{}
 	if !isShow {
 		var err error
-		clipboard, err = platform.NewClipboard(platform.DefaultClipboardLoader{})
+		clipboard, err = newClipboard()
 		if err != nil {
 			return fmt.Errorf("unable to get clipboard: %w", err)
 		}
 	}
-	val, err := getEntity(entry, cmd)
+	if sequence {
+		return clipSequence(cmd, clipboard, strings.TrimSuffix(entry, "/"), os.Stdin)
+	}
+	op := commands.Clip
+	if isShow {
+		op = commands.Show
+	}
+	val, err := getEntity(entry, cmd, op)
 	if err != nil {
 		return err
 	}
@@ -32,13 +61,13 @@ func ShowClip(cmd CommandOptions, isShow bool) error {
 		fmt.Fprintln(cmd.Writer(), val)
 		return nil
 	}
-	if err := clipboard.CopyTo(val); err != nil {
+	if err := clipTo(cmd.Writer(), clipboard, val); err != nil {
 		return fmt.Errorf("clipboard operation failed: %w", err)
 	}
 	return nil
 }
 
-func getEntity(entry string, cmd CommandOptions) (string, error) {
+func getEntity(entry string, cmd CommandOptions, op string) (string, error) {
 	base := kdbx.Base(entry)
 	dir := kdbx.Directory(entry)
 	existing, err := cmd.Transaction().Get(dir, kdbx.SecretValue)
@@ -52,5 +81,101 @@ func getEntity(entry string, cmd CommandOptions) (string, error) {
 	if !ok {
 		return "", fmt.Errorf("entity value invalid: %s", entry)
 	}
+	if err := warnRotation(os.Stderr, *existing); err != nil {
+		return "", err
+	}
+	if err := cmd.Transaction().Record(op, entry, val); err != nil {
+		return "", err
+	}
 	return val, nil
 }
+
+// clipSequence will copy the url, password, and totp code (those set) of an entry in order,
+// waiting for a paste (or enter) between each, only the final value is cleared (after the timeout),
+// the totp code is generated when its step is reached (so it is not stale after waiting)
+func clipSequence(cmd CommandOptions, clipboard platform.Clipboard, entry string, input io.Reader) error {
+	existing, err := cmd.Transaction().Get(entry, kdbx.SecretValue)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errors.New("entry does not exist")
+	}
+	var steps []clipStep
+	for _, field := range []string{kdbx.URLField, kdbx.PasswordField, kdbx.OTPField} {
+		key := strings.ToLower(field)
+		val, ok := existing.Value(key)
+		if !ok || val == "" {
+			continue
+		}
+		step := clipStep{field: key, value: val}
+		if field == kdbx.OTPField {
+			generator, err := totp.New(val)
+			if err != nil {
+				return err
+			}
+			step.otp = &generator
+		}
+		steps = append(steps, step)
+	}
+	if len(steps) == 0 {
+		return fmt.Errorf("no values to copy in sequence: %s", entry)
+	}
+	if err := warnRotation(os.Stderr, *existing); err != nil {
+		return err
+	}
+	// NOTE: enter is closed when the input ends (e.g. ctrl+d), which stops the sequence, done
+	// releases a pending send once the sequence is over
+	enter := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(enter)
+		scanner := bufio.NewScanner(input)
+		for scanner.Scan() {
+			select {
+			case enter <- struct{}{}:
+			case <-done:
+				return
+			}
+		}
+	}()
+	w := cmd.Writer()
+	last := len(steps) - 1
+	for idx, step := range steps {
+		value := step.value
+		if step.otp != nil {
+			var err error
+			if step.otp.IsHOTP() {
+				value, err = nextHOTP(cmd, entry, *step.otp)
+			} else {
+				value, err = step.otp.Code()
+			}
+			if err != nil {
+				clipboard.CopyTo("")
+				return err
+			}
+		}
+		if err := cmd.Transaction().Record(commands.Clip, kdbx.NewPath(entry, step.field), value); err != nil {
+			return err
+		}
+		if idx == last {
+			fmt.Fprintf(w, "-> %s copied\n", step.field)
+			if err := clipTo(w, clipboard, value); err != nil {
+				return fmt.Errorf("clipboard operation failed: %w", err)
+			}
+			break
+		}
+		fmt.Fprintf(w, "-> %s copied, paste (or press enter) to continue\n", step.field)
+		next, err := clipboard.CopyOnce(value, enter)
+		if err != nil {
+			clipboard.CopyTo("")
+			return fmt.Errorf("clipboard operation failed: %w", err)
+		}
+		if !next {
+			clipboard.CopyTo("")
+			return errors.New("input closed, clipboard sequence stopped")
+		}
+	}
+	return nil
+}
@@ -3,9 +3,13 @@ package app_test
 import (
 	"bytes"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/enckse/lockbox/internal/app"
+	"github.com/enckse/lockbox/internal/config/store"
+	"github.com/enckse/lockbox/internal/kdbx"
 )
 
 func TestShowClip(t *testing.T) {
@@ -34,3 +38,61 @@ func TestShowClip(t *testing.T) {
 		t.Errorf("invalid error: %v", err)
 	}
 }
+
+func TestClipSequence(t *testing.T) {
+	defer store.Clear()
+	m := newMockCommand(t)
+	fullSetup(t, true).Insert(kdbx.NewPath("test", "seq"), map[string]string{"url": "https://example.com", "password": "pass", "otp": "5ae472abqdekjqykoyxk7hvc2leklq5n"})
+	copied := filepath.Join(t.TempDir(), "copied")
+	store.SetArray("LOCKBOX_CLIP_COPY", []string{"/bin/sh", "-c", "cat >> " + copied + "; echo >> " + copied})
+	store.SetInt64("LOCKBOX_CLIP_TIMEOUT", 0)
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("invalid pipe: %v", err)
+	}
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() {
+		os.Stdin = stdin
+	}()
+	w.WriteString("\n\n")
+	w.Close()
+	m.args = []string{"-sequence", "test/seq/"}
+	if err := app.ShowClip(m, false); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	b, err := os.ReadFile(copied)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if err != nil || len(lines) != 3 || lines[0] != "https://example.com" || lines[1] != "pass" || len(lines[2]) != 6 {
+		t.Errorf("invalid sequence: %v %v", lines, err)
+	}
+	if m.buf.String() != "-> url copied, paste (or press enter) to continue\n-> password copied, paste (or press enter) to continue\n-> otp copied\n" {
+		t.Errorf("invalid output: %s", m.buf.String())
+	}
+	os.Remove(copied)
+	m.buf.Reset()
+	r, w, err = os.Pipe()
+	if err != nil {
+		t.Fatalf("invalid pipe: %v", err)
+	}
+	os.Stdin = r
+	w.WriteString("\n")
+	w.Close()
+	m.args = []string{"-sequence", "test/seq"}
+	if err := app.ShowClip(m, false); err == nil || err.Error() != "input closed, clipboard sequence stopped" {
+		t.Errorf("invalid error: %v", err)
+	}
+	b, err = os.ReadFile(copied)
+	if err != nil || string(b) != "https://example.com\npass\n\n" {
+		t.Errorf("invalid sequence: %q %v", string(b), err)
+	}
+	m.args = []string{"-sequence", "test/missing"}
+	if err := app.ShowClip(m, false); err == nil || err.Error() != "entry does not exist" {
+		t.Errorf("invalid error: %v", err)
+	}
+	fullSetup(t, true).Insert(kdbx.NewPath("test", "empty"), map[string]string{"notes": "text"})
+	m.args = []string{"-sequence", "test/empty"}
+	if err := app.ShowClip(m, false); err == nil || err.Error() != "no values to copy in sequence: test/empty" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
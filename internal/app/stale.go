@@ -0,0 +1,185 @@
+// Package app handles password age/rotation reporting
+package app
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/enckse/lockbox/internal/app/commands"
+	"github.com/enckse/lockbox/internal/config"
+	"github.com/enckse/lockbox/internal/kdbx"
+)
+
+const (
+	rotationDay  = 24 * time.Hour
+	rotationWeek = 7 * rotationDay
+)
+
+type (
+	rotationPolicy struct {
+		glob   string
+		maxAge time.Duration
+	}
+	staleEntry struct {
+		path   string
+		age    time.Duration
+		policy rotationPolicy
+	}
+)
+
+// Stale will list entries that are overdue for rotation (oldest first)
+func Stale(cmd CommandOptions) error {
+	args := cmd.Args()
+	if len(args) > 1 {
+		return errors.New("too many arguments (none or filter)")
+	}
+	var filter string
+	if len(args) == 1 {
+		filter = args[0]
+	}
+	policies, err := readRotationPolicies()
+	if err != nil {
+		return err
+	}
+	if len(policies) == 0 {
+		return errors.New("no rotation policies configured")
+	}
+	hasFilter, selector := createFilter(filter)
+	e, err := cmd.Transaction().QueryCallback(kdbx.QueryOptions{Mode: kdbx.ListMode, Values: kdbx.BlankValue})
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var stale []staleEntry
+	for item, err := range e {
+		if err != nil {
+			return err
+		}
+		if hasFilter {
+			ok, err := selector(filter, item.Path)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+		}
+		entry, ok, err := checkRotation(policies, item, now)
+		if err != nil {
+			return err
+		}
+		if ok {
+			stale = append(stale, entry)
+		}
+	}
+	if err := cmd.Transaction().Record(commands.Stale, filter, ""); err != nil {
+		return err
+	}
+	slices.SortStableFunc(stale, func(a, b staleEntry) int {
+		return cmp.Compare(b.age, a.age)
+	})
+	w := cmd.Writer()
+	for _, entry := range stale {
+		fmt.Fprintf(w, "%s (%s > %s)\n", entry.path, formatRotationAge(entry.age), formatRotationAge(entry.policy.maxAge))
+	}
+	return nil
+}
+
+func readRotationPolicies() ([]rotationPolicy, error) {
+	var policies []rotationPolicy
+	table := config.EnvRotationPolicies.Get()
+	for _, glob := range slices.Sorted(maps.Keys(table)) {
+		age := table[glob]
+		if strings.TrimSpace(glob) == "" {
+			return nil, fmt.Errorf("invalid rotation policy: %s", age)
+		}
+		if _, err := kdbx.Glob(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid rotation policy glob: %s", glob)
+		}
+		maxAge, err := parseRotationAge(age)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, rotationPolicy{glob: glob, maxAge: maxAge})
+	}
+	return policies, nil
+}
+
+func parseRotationAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	unit := rotationDay
+	switch {
+	case strings.HasSuffix(value, "d"):
+	case strings.HasSuffix(value, "w"):
+		unit = rotationWeek
+	default:
+		return 0, fmt.Errorf("invalid rotation age (expected days 'd' or weeks 'w'): %s", value)
+	}
+	count, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || count <= 0 {
+		return 0, fmt.Errorf("invalid rotation age (expected days 'd' or weeks 'w'): %s", value)
+	}
+	return time.Duration(count) * unit, nil
+}
+
+func formatRotationAge(age time.Duration) string {
+	return fmt.Sprintf("%dd", int(age/rotationDay))
+}
+
+func findRotationPolicy(policies []rotationPolicy, path string) (rotationPolicy, bool) {
+	var globs []string
+	for _, policy := range policies {
+		globs = append(globs, policy.glob)
+	}
+	glob, ok := kdbx.MatchGlob(globs, path)
+	if !ok {
+		return rotationPolicy{}, false
+	}
+	idx := slices.IndexFunc(policies, func(p rotationPolicy) bool {
+		return p.glob == glob
+	})
+	return policies[idx], true
+}
+
+func checkRotation(policies []rotationPolicy, entity kdbx.Entity, now time.Time) (staleEntry, bool, error) {
+	policy, ok := findRotationPolicy(policies, entity.Path)
+	mod := entity.PasswordModTime
+	if mod == "" {
+		mod = entity.ModTime
+	}
+	if !ok || mod == "" {
+		return staleEntry{}, false, nil
+	}
+	modTime, err := time.Parse(config.ModTimeFormat, mod)
+	if err != nil {
+		return staleEntry{}, false, fmt.Errorf("invalid modtime for %s: %w", entity.Path, err)
+	}
+	age := now.Sub(modTime)
+	if age <= policy.maxAge {
+		return staleEntry{}, false, nil
+	}
+	return staleEntry{path: entity.Path, age: age, policy: policy}, true, nil
+}
+
+func warnRotation(w io.Writer, entity kdbx.Entity) error {
+	if !config.EnvRotationWarn.Get() {
+		return nil
+	}
+	policies, err := readRotationPolicies()
+	if err != nil {
+		return err
+	}
+	entry, ok, err := checkRotation(policies, entity, time.Now())
+	if err != nil || !ok {
+		return err
+	}
+	fmt.Fprintf(w, "warning: %s is overdue for rotation (%s > %s)\n", entry.path, formatRotationAge(entry.age), formatRotationAge(entry.policy.maxAge))
+	return nil
+}
@@ -0,0 +1,114 @@
+package app_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/enckse/lockbox/internal/app"
+	"github.com/enckse/lockbox/internal/config"
+	"github.com/enckse/lockbox/internal/config/store"
+	"github.com/enckse/lockbox/internal/kdbx"
+)
+
+func newMockStale(t *testing.T) *mockCommand {
+	setup(t)
+	now := time.Now()
+	for _, item := range []struct {
+		path string
+		days int
+	}{
+		{kdbx.NewPath("prod", "db"), 100},
+		{kdbx.NewPath("prod", "api"), 200},
+		{kdbx.NewPath("prod", "new"), 10},
+		{kdbx.NewPath("personal", "mail"), 400},
+		{kdbx.NewPath("other", "old"), 1000},
+	} {
+		store.SetString("LOCKBOX_DEFAULTS_MODTIME", now.Add(time.Duration(-item.days)*24*time.Hour).Format(config.ModTimeFormat))
+		fullSetup(t, true).Insert(item.path, map[string]string{"password": "pass"})
+	}
+	store.Clear()
+	fullSetup(t, true)
+	return &mockCommand{t: t, confirmed: false, confirm: true}
+}
+
+func TestStale(t *testing.T) {
+	m := newMockStale(t)
+	m.args = []string{"a", "b"}
+	if err := app.Stale(m); err == nil || err.Error() != "too many arguments (none or filter)" {
+		t.Errorf("invalid error: %v", err)
+	}
+	m.args = []string{}
+	if err := app.Stale(m); err == nil || err.Error() != "no rotation policies configured" {
+		t.Errorf("invalid error: %v", err)
+	}
+	store.SetTable("LOCKBOX_ROTATION_POLICIES", map[string]string{" ": "90d"})
+	if err := app.Stale(m); err == nil || err.Error() != "invalid rotation policy: 90d" {
+		t.Errorf("invalid error: %v", err)
+	}
+	store.SetTable("LOCKBOX_ROTATION_POLICIES", map[string]string{"prod": "90"})
+	if err := app.Stale(m); err == nil || err.Error() != "invalid rotation age (expected days 'd' or weeks 'w'): 90" {
+		t.Errorf("invalid error: %v", err)
+	}
+	store.SetTable("LOCKBOX_ROTATION_POLICIES", map[string]string{"a/[": "90d"})
+	if err := app.Stale(m); err == nil || err.Error() != "invalid rotation policy glob: a/[" {
+		t.Errorf("invalid error: %v", err)
+	}
+	store.SetTable("LOCKBOX_ROTATION_POLICIES", map[string]string{"prod": "90d", "pers*": "52w"})
+	if err := app.Stale(m); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	expect := `personal/mail (400d > 364d)
+prod/api (200d > 90d)
+prod/db (100d > 90d)
+`
+	if m.buf.String() != expect {
+		t.Errorf("invalid stale: %s", m.buf.String())
+	}
+	m.buf.Reset()
+	m.args = []string{"prod/d*"}
+	if err := app.Stale(m); err != nil || m.buf.String() != "prod/db (100d > 90d)\n" {
+		t.Errorf("invalid stale: %s %v", m.buf.String(), err)
+	}
+	m.buf.Reset()
+	m.args = []string{}
+	store.SetTable("LOCKBOX_ROTATION_POLICIES", map[string]string{"prod": "52w", "prod/db": "1w"})
+	if err := app.Stale(m); err != nil || m.buf.String() != "prod/db (100d > 7d)\n" {
+		t.Errorf("invalid stale: %s %v", m.buf.String(), err)
+	}
+	m.buf.Reset()
+	store.SetTable("LOCKBOX_ROTATION_POLICIES", map[string]string{"*": "52w", "prod/*": "1w"})
+	if err := app.Stale(m); err != nil || m.buf.String() != "other/old (1000d > 364d)\npersonal/mail (400d > 364d)\nprod/api (200d > 7d)\nprod/db (100d > 7d)\nprod/new (10d > 7d)\n" {
+		t.Errorf("invalid stale: %s %v", m.buf.String(), err)
+	}
+}
+
+func TestStaleValueChanges(t *testing.T) {
+	m := newMockStale(t)
+	store.SetTable("LOCKBOX_ROTATION_POLICIES", map[string]string{"prod": "90d"})
+	tx := fullSetup(t, true)
+	e, err := tx.Get(kdbx.NewPath("prod", "db"), kdbx.SecretValue)
+	if err != nil || e == nil {
+		t.Fatalf("invalid entity: %v %v", e, err)
+	}
+	e.Values["notes"] = "edited"
+	if err := tx.Insert(e.Path, e.Values); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	e, err = tx.Get(kdbx.NewPath("prod", "api"), kdbx.SecretValue)
+	if err != nil || e == nil {
+		t.Fatalf("invalid entity: %v %v", e, err)
+	}
+	if err := tx.Move(kdbx.MoveRequest{Source: e, Destination: kdbx.NewPath("prod", "moved")}); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := app.Stale(m); err != nil || m.buf.String() != "prod/moved (200d > 90d)\nprod/db (100d > 90d)\n" {
+		t.Errorf("invalid stale: %s %v", m.buf.String(), err)
+	}
+	m.buf.Reset()
+	if err := tx.Insert(kdbx.NewPath("prod", "db"), map[string]string{"password": "rotated", "notes": "edited"}); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := app.Stale(m); err != nil || m.buf.String() != "prod/moved (200d > 90d)\n" {
+		t.Errorf("invalid stale: %s %v", m.buf.String(), err)
+	}
+}
@@ -3,7 +3,10 @@ package app
 
 import (
 	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"os"
 	"slices"
 	"strconv"
 	"strings"
@@ -14,6 +17,13 @@ import (
 	"github.com/enckse/lockbox/internal/config"
 	"github.com/enckse/lockbox/internal/kdbx"
 	"github.com/enckse/lockbox/internal/platform"
+	"github.com/enckse/lockbox/internal/platform/tty"
+)
+
+const (
+	hotpResyncWindow = 100
+	// deprecatedColorWindow is the largest second of the (deprecated) minute based color windows
+	deprecatedColorWindow = 59
 )
 
 var (
@@ -26,13 +36,17 @@ var (
 type (
 	// TOTPArguments are the parsed TOTP call arguments
 	TOTPArguments struct {
-		Entry string
-		Mode  string
+		Entry     string
+		Mode      string
+		Code      string
+		Migration string
+		DryRun    bool
 	}
 	// TOTPOptions are TOTP call options
 	TOTPOptions struct {
 		app   CommandOptions
 		Clear func()
+		Keys  func() (io.ReadCloser, error)
 	}
 )
 
@@ -41,6 +55,7 @@ func NewDefaultTOTPOptions(app CommandOptions) TOTPOptions {
 	return TOTPOptions{
 		app:   app,
 		Clear: clearFunc,
+		Keys:  tty.Keys,
 	}
 }
 
@@ -48,16 +63,16 @@ func clearFunc() {
 	fmt.Print("\033[H\033[2J")
 }
 
-func colorWhenRules() ([]config.TimeWindow, error) {
+func colorWhenRules(period int) ([]config.TimeWindow, error) {
 	envTime := config.EnvTOTPColorBetween.Get()
 	if slices.Compare(envTime, config.TOTPDefaultBetween) == 0 {
 		return config.TOTPDefaultColorWindow, nil
 	}
-	return ParseTimeWindow(envTime...)
+	return ParseTimeWindow(os.Stderr, period, envTime...)
 }
 
 func (args *TOTPArguments) display(opts TOTPOptions) error {
-	interactive := !slices.Contains([]string{commands.TOTPMinimal, commands.TOTPSeed, commands.TOTPURL}, args.Mode)
+	interactive := !slices.Contains([]string{commands.TOTPMinimal, commands.TOTPSeed, commands.TOTPURL, commands.TOTPResync, commands.TOTPQR, commands.TOTPVerify}, args.Mode)
 	once := args.Mode == commands.TOTPOnce
 	clipMode := args.Mode == commands.TOTPClip
 	if !interactive && clipMode {
@@ -66,7 +81,7 @@ func (args *TOTPArguments) display(opts TOTPOptions) error {
 	if !kdbx.IsLeafAttribute(args.Entry, kdbx.OTPField) {
 		return fmt.Errorf("'%s' is not a TOTP entry", args.Entry)
 	}
-	entity, err := getEntity(args.Entry, opts.app)
+	entity, err := getEntity(args.Entry, opts.app, strings.TrimSpace(fmt.Sprintf("%s %s", commands.TOTP, args.Mode)))
 	if err != nil {
 		return err
 	}
@@ -79,6 +94,16 @@ func (args *TOTPArguments) display(opts TOTPOptions) error {
 	case commands.TOTPSeed, commands.TOTPURL:
 		generator.Print(writer, args.Mode == commands.TOTPURL)
 		return nil
+	case commands.TOTPQR:
+		return writeQR(writer, generator.URL(), "")
+	case commands.TOTPVerify:
+		return args.verify(writer, generator)
+	}
+	if generator.IsHOTP() {
+		return args.hotp(opts, generator)
+	}
+	if args.Mode == commands.TOTPResync {
+		return fmt.Errorf("'%s' is not a HOTP entry", args.Entry)
 	}
 	if !interactive {
 		code, err := generator.Code()
@@ -98,12 +123,12 @@ func (args *TOTPArguments) display(opts TOTPOptions) error {
 	}
 	clipboard := platform.Clipboard{}
 	if clipMode {
-		clipboard, err = platform.NewClipboard(platform.DefaultClipboardLoader{})
+		clipboard, err = newClipboard()
 		if err != nil {
 			return err
 		}
 	}
-	colorRules, err := colorWhenRules()
+	colorRules, err := colorWhenRules(generator.Period())
 	if err != nil {
 		return err
 	}
@@ -111,6 +136,10 @@ func (args *TOTPArguments) display(opts TOTPOptions) error {
 	if err != nil {
 		return err
 	}
+	nextWithin, err := config.EnvTOTPNextWithin.Get()
+	if err != nil {
+		return err
+	}
 	allowColor := config.CanColor()
 	for {
 		if !first {
@@ -128,7 +157,7 @@ func (args *TOTPArguments) display(opts TOTPOptions) error {
 			continue
 		}
 		lastSecond = last
-		left := 60 - last
+		left := generator.Remaining(now)
 		code, err := generator.Code()
 		if err != nil {
 			return err
@@ -151,13 +180,21 @@ func (args *TOTPArguments) display(opts TOTPOptions) error {
 		}
 		outputs := []string{txt}
 		if !clipMode {
-			outputs = append(outputs, fmt.Sprintf("%s\n    %s", args.Entry, code))
+			codes := fmt.Sprintf("%s\n    %s", args.Entry, code)
+			if int64(left) <= nextWithin {
+				next, err := generator.Next()
+				if err != nil {
+					return err
+				}
+				codes = fmt.Sprintf("%s\n    %s (next)", codes, next)
+			}
+			outputs = append(outputs, codes)
 			if !once {
 				outputs = append(outputs, "-> CTRL+C to exit")
 			}
 		} else {
 			fmt.Fprintf(writer, "-> %s\n", txt)
-			return clipboard.CopyTo(code)
+			return clipTo(writer, clipboard, code)
 		}
 		if !once {
 			opts.Clear()
@@ -169,6 +206,91 @@ func (args *TOTPArguments) display(opts TOTPOptions) error {
 	}
 }
 
+// verify will check a code against the current window (and the configured windows around it)
+func (args *TOTPArguments) verify(w io.Writer, generator totp.Generator) error {
+	windows, err := config.EnvTOTPVerifyWindows.Get()
+	if err != nil {
+		return err
+	}
+	window, ok, err := generator.Verify(strings.ReplaceAll(args.Code, " ", ""), int(windows))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("code is not valid within %d windows", windows)
+	}
+	if window == 0 {
+		fmt.Fprintln(w, "valid (current window)")
+		return nil
+	}
+	fmt.Fprintf(w, "valid (%+d windows)\n", window)
+	return nil
+}
+
+// hotp will generate (or resync) a counter based code, the next counter is stored before the code is used
+func (args *TOTPArguments) hotp(opts TOTPOptions, generator totp.Generator) error {
+	writer := opts.app.Writer()
+	dir := kdbx.Directory(args.Entry)
+	if args.Mode == commands.TOTPResync {
+		counter := generator.Counter()
+		for offset := range uint64(hotpResyncWindow) {
+			code, err := generator.CodeAt(counter + offset)
+			if err != nil {
+				return err
+			}
+			if code != args.Code {
+				continue
+			}
+			if err := storeCounter(opts.app, dir, generator, counter+offset+1); err != nil {
+				return err
+			}
+			fmt.Fprintf(writer, "counter resynced: %d\n", counter+offset+1)
+			return nil
+		}
+		return fmt.Errorf("code not found within %d counters of %d", hotpResyncWindow, counter)
+	}
+	clipboard := platform.Clipboard{}
+	if args.Mode == commands.TOTPClip {
+		var err error
+		clipboard, err = newClipboard()
+		if err != nil {
+			return err
+		}
+	}
+	code, err := nextHOTP(opts.app, dir, generator)
+	if err != nil {
+		return err
+	}
+	switch args.Mode {
+	case commands.TOTPClip:
+		return clipTo(writer, clipboard, code)
+	case commands.TOTPMinimal:
+		fmt.Fprintln(writer, code)
+	default:
+		fmt.Fprintf(writer, "%s (counter %d)\n    %s\n", args.Entry, generator.Counter(), code)
+	}
+	return nil
+}
+
+// nextHOTP will get the code for the current counter (storing the next counter)
+func nextHOTP(cmd CommandOptions, dir string, generator totp.Generator) (string, error) {
+	code, err := generator.Code()
+	if err != nil {
+		return "", err
+	}
+	if err := storeCounter(cmd, dir, generator, generator.Counter()+1); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+func storeCounter(cmd CommandOptions, dir string, generator totp.Generator, counter uint64) error {
+	if config.EnvReadOnly.Get() {
+		return errors.New("hotp counter can not be stored in read-only")
+	}
+	return cmd.Transaction().UpdateOTP(dir, generator.WithCounter(counter))
+}
+
 // Do will perform the TOTP operation
 func (args *TOTPArguments) Do(opts TOTPOptions) error {
 	if args.Mode == "" {
@@ -177,8 +299,16 @@ func (args *TOTPArguments) Do(opts TOTPOptions) error {
 	if opts.Clear == nil {
 		return errors.New("invalid option functions")
 	}
-	if args.Mode == commands.TOTPList {
+	switch args.Mode {
+	case commands.TOTPList:
 		return doList(kdbx.OTPField, args.Entry, opts.app, ListEntriesMode)
+	case commands.TOTPWatch:
+		if opts.Keys == nil {
+			return errors.New("invalid option functions")
+		}
+		return args.watch(opts)
+	case commands.TOTPImport:
+		return args.importMigration(opts)
 	}
 	return args.display(opts)
 }
@@ -196,20 +326,44 @@ func NewTOTPArguments(args []string) (*TOTPArguments, error) {
 	needs := true
 	length := len(args)
 	switch sub {
-	case commands.TOTPList:
+	case commands.TOTPList, commands.TOTPWatch:
 		needs = false
 		if length != 1 {
 			needs = true
 			if length != 2 {
-				return nil, errors.New("list takes only a filter (if any)")
+				name := "list"
+				if sub == commands.TOTPWatch {
+					name = commands.TOTPWatch
+				}
+				return nil, fmt.Errorf("%s takes only a filter (if any)", name)
 			}
 		}
 	case commands.TOTPURL:
 	case commands.TOTPSeed:
+	case commands.TOTPQR:
 	case commands.TOTPShow:
 	case commands.TOTPClip:
 	case commands.TOTPMinimal:
 	case commands.TOTPOnce:
+	case commands.TOTPResync, commands.TOTPVerify:
+		if length != 3 {
+			return nil, fmt.Errorf("%s requires an entry and a code", sub)
+		}
+		opts.Code = args[2]
+		length--
+	case commands.TOTPImport:
+		set := flag.NewFlagSet(commands.TOTPImport, flag.ExitOnError)
+		set.BoolVar(&opts.DryRun, commands.TOTPImportFlags.DryRun, false, "list the entries to import without inserting")
+		if err := set.Parse(args[1:]); err != nil {
+			return nil, err
+		}
+		remaining := set.Args()
+		if len(remaining) != 2 {
+			return nil, errors.New("import requires a group and a migration url")
+		}
+		opts.Entry = remaining[0]
+		opts.Migration = remaining[1]
+		needs = false
 	default:
 		return nil, ErrUnknownTOTPMode
 	}
@@ -223,8 +377,9 @@ func NewTOTPArguments(args []string) (*TOTPArguments, error) {
 	return opts, nil
 }
 
-// ParseTimeWindow will handle parsing a window of colors for TOTP operations
-func ParseTimeWindow(windows ...string) ([]config.TimeWindow, error) {
+// ParseTimeWindow will handle parsing a window of colors for TOTP operations, windows beyond the
+// period that fit in a minute (the deprecated meaning) are moved into the period (with a warning)
+func ParseTimeWindow(w io.Writer, period int, windows ...string) ([]config.TimeWindow, error) {
 	var rules []config.TimeWindow
 	for _, item := range windows {
 		line := strings.TrimSpace(item)
@@ -243,10 +398,23 @@ func ParseTimeWindow(windows ...string) ([]config.TimeWindow, error) {
 		if err != nil {
 			return nil, err
 		}
-		if s < 0 || e < 0 || e < s || s > 59 || e > 59 {
+		if s < 0 || e < 0 || e < s {
 			return nil, fmt.Errorf("invalid time found for colorization rule: %s", line)
 		}
-		rules = append(rules, config.TimeWindow{Start: s, End: e})
+		if s >= period || e > period {
+			if e > deprecatedColorWindow {
+				return nil, fmt.Errorf("colorization rule exceeds the totp period (%ds): %s", period, line)
+			}
+			offset := s - s%period
+			s -= offset
+			e = min(e-offset, period)
+			fmt.Fprintf(w, "warning: colorization rule %s is in seconds of the minute (deprecated), using %d%s%d of the totp period (%ds)\n", line, s, config.TimeWindowSpan, e, period)
+		}
+		rule := config.TimeWindow{Start: s, End: e}
+		if slices.Contains(rules, rule) {
+			continue
+		}
+		rules = append(rules, rule)
 	}
 	if len(rules) == 0 {
 		return nil, errors.New("invalid colorization rules for totp, none found")
@@ -2,9 +2,19 @@
 package totp
 
 import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ja7ad/otp"
@@ -12,18 +22,162 @@ import (
 	"github.com/enckse/lockbox/internal/config"
 )
 
+const (
+	hotpType     = "hotp"
+	counterParam = "counter"
+	encoderParam = "encoder"
+	// steamEncoder is the (KeePassXC compatible) encoder for Steam Guard codes
+	steamEncoder  = "steam"
+	steamAlphabet = "23456789BCDFGHJKMNPQRTVWXY"
+	steamDigits   = 5
+	maxDigits     = 10
+)
+
 type (
-	// Generator is used to generate TOTP codes
+	// Generator is used to generate TOTP (or HOTP) codes
 	Generator struct {
-		secret string
-		opts   *otp.Param
-		url    *url.URL
+		secret  string
+		opts    *otp.Param
+		url     *url.URL
+		hotp    bool
+		counter uint64
+		offset  time.Duration
+		steam   bool
 	}
 )
 
-// Code will generate a new code for the specified TOTP object
+// ClockOffset is the (configured) offset applied to the clock when generating TOTP codes
+func ClockOffset() (time.Duration, error) {
+	offset, err := time.ParseDuration(config.EnvTOTPClockOffset.Get())
+	if err != nil {
+		return 0, fmt.Errorf("invalid totp clock offset: %w", err)
+	}
+	return offset, nil
+}
+
+func (g Generator) now() time.Time {
+	return time.Now().Add(g.offset)
+}
+
+// Period is the TOTP period (seconds)
+func (g Generator) Period() int {
+	return int(g.opts.Period)
+}
+
+func (g Generator) period() time.Duration {
+	return time.Duration(g.opts.Period) * time.Second
+}
+
+// Code will generate a new code for the specified TOTP object (HOTP objects use the current counter)
 func (g Generator) Code() (string, error) {
-	return otp.GenerateTOTP(g.secret, time.Now(), g.opts)
+	if g.hotp {
+		return g.CodeAt(g.counter)
+	}
+	return g.codeFor(g.now())
+}
+
+// Next will generate the TOTP code that follows the current code
+func (g Generator) Next() (string, error) {
+	return g.codeFor(g.now().Add(g.period()))
+}
+
+func (g Generator) codeFor(t time.Time) (string, error) {
+	return g.CodeAt(uint64(t.Unix()) / uint64(g.opts.Period))
+}
+
+// Remaining is the time (seconds) until the TOTP code at a time expires (clock offset applied)
+func (g Generator) Remaining(now time.Time) int {
+	period := int64(g.opts.Period)
+	return int(period - now.Add(g.offset).Unix()%period)
+}
+
+// Verify will check a TOTP code against the current period and up to windows periods before
+// (and after) it, returning the (nearest) matching window (0 is the current period)
+func (g Generator) Verify(code string, windows int) (int, bool, error) {
+	if g.hotp {
+		return 0, false, errors.New("codes can only be verified for TOTP tokens")
+	}
+	if g.steam {
+		code = strings.ToUpper(code)
+	}
+	now := g.now()
+	for distance := range windows + 1 {
+		for _, window := range []int{-distance, distance} {
+			expect, err := g.codeFor(now.Add(time.Duration(window) * g.period()))
+			if err != nil {
+				return 0, false, err
+			}
+			if subtle.ConstantTimeCompare([]byte(expect), []byte(code)) == 1 {
+				return window, true, nil
+			}
+			if distance == 0 {
+				break
+			}
+		}
+	}
+	return 0, false, nil
+}
+
+// CodeAt will generate the code for a counter (for TOTP the counter is the time step)
+func (g Generator) CodeAt(counter uint64) (string, error) {
+	if g.steam {
+		return g.steamCode(counter)
+	}
+	return otp.GenerateHOTP(g.secret, counter, g.opts)
+}
+
+// steamCode is the (RFC 4226) truncated value encoded with the Steam Guard alphabet
+func (g Generator) steamCode(counter uint64) (string, error) {
+	secret, err := otp.DecodeSecret(g.secret)
+	if err != nil {
+		return "", err
+	}
+	var fxn func() hash.Hash
+	switch g.opts.Algorithm {
+	case otp.SHA256:
+		fxn = sha256.New
+	case otp.SHA512:
+		fxn = sha512.New
+	default:
+		fxn = sha1.New
+	}
+	mac := hmac.New(fxn, secret)
+	if err := binary.Write(mac, binary.BigEndian, counter); err != nil {
+		return "", err
+	}
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0xf
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	code := make([]byte, g.opts.Digits)
+	for i := range code {
+		code[i] = steamAlphabet[value%uint32(len(steamAlphabet))]
+		value /= uint32(len(steamAlphabet))
+	}
+	return string(code), nil
+}
+
+// IsHOTP indicates the generator is counter (not time) based
+func (g Generator) IsHOTP() bool {
+	return g.hotp
+}
+
+// Counter is the current HOTP counter
+func (g Generator) Counter() uint64 {
+	return g.counter
+}
+
+// WithCounter will get the (HOTP) otpauth url set to a new counter, for storing
+func (g Generator) WithCounter(counter uint64) string {
+	u := *g.url
+	query := u.Query()
+	query.Set(counterParam, strconv.FormatUint(counter, 10))
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// URL is the otpauth url of the generator
+func (g Generator) URL() string {
+	return g.url.String()
 }
 
 // Print will print information about the generator to the writer
@@ -33,7 +187,14 @@ func (g Generator) Print(w io.Writer, details bool) {
 		fmt.Fprintf(w, "seed:      %s\n", g.secret)
 		fmt.Fprintf(w, "digits:    %d\n", g.opts.Digits)
 		fmt.Fprintf(w, "algorithm: %s\n", g.opts.Algorithm)
-		fmt.Fprintf(w, "period:    %d\n", g.opts.Period)
+		if g.steam {
+			fmt.Fprintf(w, "encoder:   %s\n", steamEncoder)
+		}
+		if g.hotp {
+			fmt.Fprintf(w, "counter:   %d\n", g.counter)
+		} else {
+			fmt.Fprintf(w, "period:    %d\n", g.opts.Period)
+		}
 		return
 	}
 	fmt.Fprintln(w, g.secret)
@@ -45,8 +206,13 @@ func New(input string) (Generator, error) {
 	if err != nil {
 		return Generator{}, err
 	}
-
-	obj, err := otp.ParseOTPAuthURL(u)
+	label := *u
+	// NOTE: the issuer prefix of a label is optional, the parser requires it
+	if !strings.Contains(label.Path, ":") {
+		label.Path = "/:" + strings.TrimPrefix(label.Path, "/")
+		label.RawPath = ""
+	}
+	obj, err := otp.ParseOTPAuthURL(&label)
 	if err != nil {
 		return Generator{}, err
 	}
@@ -57,5 +223,31 @@ func New(input string) (Generator, error) {
 	wrapper.opts.Digits = obj.Digits
 	wrapper.opts.Period = obj.Period
 	wrapper.url = u
+	switch encoder := u.Query().Get(encoderParam); strings.ToLower(encoder) {
+	case "":
+	case steamEncoder:
+		wrapper.steam = true
+		wrapper.opts.Digits = steamDigits
+	default:
+		return Generator{}, fmt.Errorf("unsupported otp encoder: %s", encoder)
+	}
+	if wrapper.opts.Digits < 1 || wrapper.opts.Digits > maxDigits {
+		return Generator{}, fmt.Errorf("invalid otp digits: %d", wrapper.opts.Digits)
+	}
+	if strings.EqualFold(u.Host, hotpType) {
+		wrapper.hotp = true
+		if counter := u.Query().Get(counterParam); counter != "" {
+			wrapper.counter, err = strconv.ParseUint(counter, 10, 64)
+			if err != nil {
+				return Generator{}, fmt.Errorf("invalid hotp counter: %s", counter)
+			}
+		}
+	} else if wrapper.opts.Period == 0 {
+		return Generator{}, errors.New("invalid totp period: 0")
+	}
+	wrapper.offset, err = ClockOffset()
+	if err != nil {
+		return Generator{}, err
+	}
 	return wrapper, nil
 }
@@ -2,10 +2,15 @@ package totp_test
 
 import (
 	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/enckse/lockbox/internal/app/totp"
+	"github.com/enckse/lockbox/internal/config/store"
 )
 
 func TestPrint(t *testing.T) {
@@ -53,3 +58,221 @@ func TestNew(t *testing.T) {
 		t.Errorf("invalid error: %v", err)
 	}
 }
+
+func TestHOTP(t *testing.T) {
+	generator, err := totp.New("otpauth://hotp/lb:vpn?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&counter=3")
+	if err != nil || !generator.IsHOTP() || generator.Counter() != 3 {
+		t.Errorf("invalid generator: %v %v", generator, err)
+	}
+	for counter, expect := range []string{"755224", "287082", "359152", "969429", "338314", "254676"} {
+		if code, err := generator.CodeAt(uint64(counter)); err != nil || code != expect {
+			t.Errorf("invalid code: %d %s %v", counter, code, err)
+		}
+	}
+	if code, err := generator.Code(); err != nil || code != "969429" {
+		t.Errorf("invalid code: %s %v", code, err)
+	}
+	next, err := totp.New(generator.WithCounter(4))
+	if err != nil || next.Counter() != 4 || generator.Counter() != 3 {
+		t.Errorf("invalid generator: %v %v", next, err)
+	}
+	var buf bytes.Buffer
+	next.Print(&buf, true)
+	if !strings.Contains(buf.String(), "counter:   4\n") || strings.Contains(buf.String(), "period") {
+		t.Errorf("invalid buffer: %s", buf.String())
+	}
+	if _, err := totp.New("otpauth://hotp/lb:vpn?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&counter=x"); err == nil || err.Error() != "invalid hotp counter: x" {
+		t.Errorf("invalid error: %v", err)
+	}
+	generator, _ = totp.New("5ae472abqdekjqykoyxk7hvc2leklq5n")
+	if generator.IsHOTP() {
+		t.Error("should be totp")
+	}
+}
+
+func TestPeriod(t *testing.T) {
+	generator, _ := totp.New("otpauth://totp/lb:a?secret=5ae472abqdekjqykoyxk7hvc2leklq5n&period=90")
+	for seconds, expect := range map[int64]int{0: 90, 1: 89, 89: 1, 90: 90, 100: 80} {
+		if left := generator.Remaining(time.Unix(seconds, 0)); left != expect {
+			t.Errorf("invalid remaining: %d %d", seconds, left)
+		}
+	}
+	generator, _ = totp.New("5ae472abqdekjqykoyxk7hvc2leklq5n")
+	if left := generator.Remaining(time.Unix(31, 0)); left != 29 {
+		t.Errorf("invalid remaining: %d", left)
+	}
+	if next, err := generator.Next(); err != nil || len(next) != 6 {
+		t.Errorf("invalid next: %s %v", next, err)
+	}
+	if _, err := totp.New("otpauth://totp/lb:a?secret=5ae472abqdekjqykoyxk7hvc2leklq5n&period=0"); err == nil || err.Error() != "invalid totp period: 0" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestAlgorithms(t *testing.T) {
+	// RFC 6238 (appendix B) test vectors
+	secrets := map[string]string{
+		"SHA1":   "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		"SHA256": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZA",
+		"SHA512": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNA",
+	}
+	vectors := map[string]map[int64]string{
+		"SHA1":   {59: "94287082", 1111111109: "07081804", 1234567890: "89005924", 20000000000: "65353130"},
+		"SHA256": {59: "46119246", 1111111109: "68084774", 1234567890: "91819424", 20000000000: "77737706"},
+		"SHA512": {59: "90693936", 1111111109: "25091201", 1234567890: "93441116", 20000000000: "47863826"},
+	}
+	for algorithm, expect := range vectors {
+		generator, err := totp.New(fmt.Sprintf("otpauth://totp/lb:rfc?secret=%s&algorithm=%s&digits=8&period=30", secrets[algorithm], algorithm))
+		if err != nil {
+			t.Errorf("invalid error: %v", err)
+			continue
+		}
+		for seconds, code := range expect {
+			if actual, err := generator.CodeAt(uint64(seconds / 30)); err != nil || actual != code {
+				t.Errorf("invalid code: %s %d %s %v", algorithm, seconds, actual, err)
+			}
+		}
+		if code, err := generator.Code(); err != nil || len(code) != 8 {
+			t.Errorf("invalid code: %s %v", code, err)
+		}
+	}
+	for _, digits := range []string{"0", "11"} {
+		if _, err := totp.New("otpauth://totp/lb:a?secret=5ae472abqdekjqykoyxk7hvc2leklq5n&digits=" + digits); err == nil || err.Error() != "invalid otp digits: "+digits {
+			t.Errorf("invalid error: %v", err)
+		}
+	}
+}
+
+func TestSteam(t *testing.T) {
+	// KeePassXC (Steam Guard) test vectors
+	generator, err := totp.New("otpauth://totp/Steam:test?secret=63BEDWCQZKTQWPESARIERL5DTTQFCJTK&issuer=Steam&encoder=steam")
+	if err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	for seconds, expect := range map[uint64]string{1511200518: "FR8RV", 1511200714: "9P3VP"} {
+		if code, err := generator.CodeAt(seconds / 30); err != nil || code != expect {
+			t.Errorf("invalid code: %d %s %v", seconds, code, err)
+		}
+	}
+	code, err := generator.Code()
+	if err != nil || len(code) != 5 || strings.Trim(code, "23456789BCDFGHJKMNPQRTVWXY") != "" {
+		t.Errorf("invalid code: %s %v", code, err)
+	}
+	if window, ok, err := generator.Verify(strings.ToLower(code), 0); err != nil || !ok || window != 0 {
+		t.Errorf("invalid verify: %d %v %v", window, ok, err)
+	}
+	var buf bytes.Buffer
+	generator.Print(&buf, true)
+	if !strings.Contains(buf.String(), "digits:    5\n") || !strings.Contains(buf.String(), "encoder:   steam\n") {
+		t.Errorf("invalid buffer: %s", buf.String())
+	}
+	if _, err := totp.New("otpauth://totp/Steam:test?secret=63BEDWCQZKTQWPESARIERL5DTTQFCJTK&encoder=xyz"); err == nil || err.Error() != "unsupported otp encoder: xyz" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	defer store.Clear()
+	store.Clear()
+	generator, _ := totp.New("5ae472abqdekjqykoyxk7hvc2leklq5n")
+	code, _ := generator.Code()
+	next, _ := generator.Next()
+	if window, ok, err := generator.Verify(code, 0); err != nil || !ok || window != 0 {
+		t.Errorf("invalid verify: %d %v %v", window, ok, err)
+	}
+	if _, ok, err := generator.Verify(next, 0); err != nil || ok {
+		t.Errorf("invalid verify: %v %v", ok, err)
+	}
+	if window, ok, err := generator.Verify(next, 1); err != nil || !ok || window != 1 {
+		t.Errorf("invalid verify: %d %v %v", window, ok, err)
+	}
+	store.SetString("LOCKBOX_TOTP_CLOCK_OFFSET", "-30s")
+	behind, _ := totp.New("5ae472abqdekjqykoyxk7hvc2leklq5n")
+	if window, ok, err := behind.Verify(code, 1); err != nil || !ok || window != 1 {
+		t.Errorf("invalid verify: %d %v %v", window, ok, err)
+	}
+	if prior, _ := behind.Next(); prior != code {
+		t.Errorf("invalid offset code: %s %s", prior, code)
+	}
+	if left := behind.Remaining(time.Unix(31, 0)); left != 29 {
+		t.Errorf("invalid remaining: %d", left)
+	}
+	store.SetString("LOCKBOX_TOTP_CLOCK_OFFSET", "10s")
+	if offset, err := totp.ClockOffset(); err != nil || offset != 10*time.Second {
+		t.Errorf("invalid offset: %v %v", offset, err)
+	}
+	store.SetString("LOCKBOX_TOTP_CLOCK_OFFSET", "abc")
+	if _, err := totp.New("5ae472abqdekjqykoyxk7hvc2leklq5n"); err == nil || !strings.HasPrefix(err.Error(), "invalid totp clock offset: ") {
+		t.Errorf("invalid error: %v", err)
+	}
+	store.Clear()
+	hotp, _ := totp.New("otpauth://hotp/lb:vpn?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&counter=3")
+	if _, _, err := hotp.Verify("969429", 1); err == nil || err.Error() != "codes can only be verified for TOTP tokens" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func protoField(number int, data []byte) []byte {
+	return append([]byte{byte(number<<3 | 2), byte(len(data))}, data...)
+}
+
+func protoVarint(number int, value byte) []byte {
+	return []byte{byte(number << 3), value}
+}
+
+func migrationURL(accounts ...[]byte) string {
+	var payload []byte
+	for _, a := range accounts {
+		payload = append(payload, protoField(1, a)...)
+	}
+	payload = append(payload, protoVarint(2, 1)...)
+	return "otpauth-migration://offline?data=" + url.QueryEscape(base64.StdEncoding.EncodeToString(payload))
+}
+
+func TestParseMigration(t *testing.T) {
+	var totpAccount, hotpAccount []byte
+	totpAccount = append(totpAccount, protoField(1, []byte("Hello!\xde\xad\xbe\xef"))...)
+	totpAccount = append(totpAccount, protoField(2, []byte("Example:alice@example.com"))...)
+	totpAccount = append(totpAccount, protoField(3, []byte("Example"))...)
+	totpAccount = append(totpAccount, protoVarint(4, 1)...)
+	totpAccount = append(totpAccount, protoVarint(5, 1)...)
+	totpAccount = append(totpAccount, protoVarint(6, 2)...)
+	hotpAccount = append(hotpAccount, protoField(1, []byte("12345678901234567890"))...)
+	hotpAccount = append(hotpAccount, protoField(2, []byte("vpn"))...)
+	hotpAccount = append(hotpAccount, protoVarint(4, 2)...)
+	hotpAccount = append(hotpAccount, protoVarint(5, 2)...)
+	hotpAccount = append(hotpAccount, protoVarint(6, 1)...)
+	hotpAccount = append(hotpAccount, protoVarint(7, 5)...)
+	accounts, err := totp.ParseMigration(migrationURL(totpAccount, hotpAccount))
+	if err != nil || len(accounts) != 2 {
+		t.Fatalf("invalid accounts: %v %v", accounts, err)
+	}
+	if accounts[0].Name != "alice@example.com" || accounts[0].Issuer != "Example" || accounts[0].URL != "otpauth://totp/Example:alice@example.com?algorithm=SHA1&digits=6&issuer=Example&period=30&secret=JBSWY3DPEHPK3PXP" {
+		t.Errorf("invalid account: %v", accounts[0])
+	}
+	if accounts[1].Name != "vpn" || accounts[1].Issuer != "" || accounts[1].URL != "otpauth://hotp/vpn?algorithm=SHA256&counter=5&digits=8&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" {
+		t.Errorf("invalid account: %v", accounts[1])
+	}
+	generator, err := totp.New(accounts[1].URL)
+	if err != nil || !generator.IsHOTP() || generator.Counter() != 5 {
+		t.Errorf("invalid generator: %v", err)
+	}
+	if _, err := totp.ParseMigration("otpauth://totp/a:b"); err == nil || err.Error() != "not a migration url, expected otpauth-migration://offline" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := totp.ParseMigration("otpauth-migration://offline?data="); err == nil || err.Error() != "migration url has no data" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := totp.ParseMigration(migrationURL()); err == nil || err.Error() != "no accounts in migration data" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := totp.ParseMigration(migrationURL(protoField(2, []byte("x")))); err == nil || err.Error() != "invalid migration account, no secret: x" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := totp.ParseMigration(migrationURL(append(protoField(1, []byte("x")), protoVarint(4, 4)...))); err == nil || err.Error() != "unsupported migration algorithm for : 4" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := totp.ParseMigration("otpauth-migration://offline?data=CgU"); err == nil || err.Error() != "invalid migration payload: bad length" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
@@ -0,0 +1,213 @@
+// Package totp handles authenticator migration (export) payloads
+package totp
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	migrationScheme = "otpauth-migration"
+	migrationHost   = "offline"
+	migrationData   = "data"
+	// migration payload fields (protobuf field numbers)
+	payloadParameters = 1
+	paramSecret       = 1
+	paramName         = 2
+	paramIssuer       = 3
+	paramAlgorithm    = 4
+	paramDigits       = 5
+	paramType         = 6
+	paramCounter      = 7
+	// protobuf wire types
+	wireVarint = 0
+	wireI64    = 1
+	wireLen    = 2
+	wireI32    = 5
+)
+
+var (
+	migrationAlgorithms = map[uint64]string{0: "SHA1", 1: "SHA1", 2: "SHA256", 3: "SHA512"}
+	migrationDigits     = map[uint64]int{0: 6, 1: 6, 2: 8}
+	migrationTypes      = map[uint64]string{0: "totp", 1: hotpType, 2: "totp"}
+)
+
+type (
+	// MigrationAccount is an account from an authenticator migration (export) payload
+	MigrationAccount struct {
+		Name   string
+		Issuer string
+		// URL is the otpauth url for the account
+		URL string
+	}
+	protoField struct {
+		number int
+		varint uint64
+		bytes  []byte
+	}
+)
+
+func readProto(data []byte) ([]protoField, error) {
+	var fields []protoField
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errors.New("invalid migration payload: bad field key")
+		}
+		data = data[n:]
+		field := protoField{number: int(key >> 3)}
+		switch key & 7 {
+		case wireVarint:
+			field.varint, n = binary.Uvarint(data)
+			if n <= 0 {
+				return nil, errors.New("invalid migration payload: bad varint")
+			}
+			data = data[n:]
+		case wireLen:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return nil, errors.New("invalid migration payload: bad length")
+			}
+			field.bytes = data[n : n+int(length)]
+			data = data[n+int(length):]
+		case wireI64, wireI32:
+			size := 8
+			if key&7 == wireI32 {
+				size = 4
+			}
+			if len(data) < size {
+				return nil, errors.New("invalid migration payload: truncated field")
+			}
+			data = data[size:]
+			continue
+		default:
+			return nil, fmt.Errorf("invalid migration payload: unsupported wire type %d", key&7)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func readAccount(data []byte) (MigrationAccount, error) {
+	fields, err := readProto(data)
+	if err != nil {
+		return MigrationAccount{}, err
+	}
+	var secret []byte
+	var account MigrationAccount
+	var algorithm, digits, otpType, counter uint64
+	for _, f := range fields {
+		switch f.number {
+		case paramSecret:
+			secret = f.bytes
+		case paramName:
+			account.Name = string(f.bytes)
+		case paramIssuer:
+			account.Issuer = string(f.bytes)
+		case paramAlgorithm:
+			algorithm = f.varint
+		case paramDigits:
+			digits = f.varint
+		case paramType:
+			otpType = f.varint
+		case paramCounter:
+			counter = f.varint
+		}
+	}
+	if len(secret) == 0 {
+		return MigrationAccount{}, fmt.Errorf("invalid migration account, no secret: %s", account.Name)
+	}
+	algo, ok := migrationAlgorithms[algorithm]
+	if !ok {
+		return MigrationAccount{}, fmt.Errorf("unsupported migration algorithm for %s: %d", account.Name, algorithm)
+	}
+	digitCount, ok := migrationDigits[digits]
+	if !ok {
+		return MigrationAccount{}, fmt.Errorf("unsupported migration digits for %s: %d", account.Name, digits)
+	}
+	kind, ok := migrationTypes[otpType]
+	if !ok {
+		return MigrationAccount{}, fmt.Errorf("unsupported migration type for %s: %d", account.Name, otpType)
+	}
+	// NOTE: names are often exported as 'issuer:account'
+	if issuer, name, ok := strings.Cut(account.Name, ":"); ok && (account.Issuer == "" || account.Issuer == issuer) {
+		account.Issuer = issuer
+		account.Name = strings.TrimSpace(name)
+	}
+	v := url.Values{}
+	v.Set("secret", base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret))
+	v.Set("algorithm", algo)
+	v.Set("digits", strconv.Itoa(digitCount))
+	if account.Issuer != "" {
+		v.Set("issuer", account.Issuer)
+	}
+	if kind == hotpType {
+		v.Set(counterParam, strconv.FormatUint(counter, 10))
+	} else {
+		v.Set("period", "30")
+	}
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     kind,
+		Path:     "/" + account.Label(),
+		RawQuery: v.Encode(),
+	}
+	account.URL = u.String()
+	return account, nil
+}
+
+// Label is the account label ('issuer:name', or only the name without an issuer)
+func (m MigrationAccount) Label() string {
+	if m.Issuer == "" {
+		return m.Name
+	}
+	return m.Issuer + ":" + m.Name
+}
+
+// ParseMigration will read the accounts from an 'otpauth-migration://offline?data=...' url
+func ParseMigration(input string) ([]MigrationAccount, error) {
+	u, err := url.Parse(strings.TrimSpace(input))
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != migrationScheme || u.Host != migrationHost {
+		return nil, fmt.Errorf("not a migration url, expected %s://%s", migrationScheme, migrationHost)
+	}
+	// NOTE: unescaped '+' in the base64 data is read as a space
+	data := strings.ReplaceAll(u.Query().Get(migrationData), " ", "+")
+	if data == "" {
+		return nil, errors.New("migration url has no data")
+	}
+	payload, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		payload, err = base64.RawStdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration data: %w", err)
+		}
+	}
+	fields, err := readProto(payload)
+	if err != nil {
+		return nil, err
+	}
+	var accounts []MigrationAccount
+	for _, f := range fields {
+		if f.number != payloadParameters {
+			continue
+		}
+		account, err := readAccount(f.bytes)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	if len(accounts) == 0 {
+		return nil, errors.New("no accounts in migration data")
+	}
+	return accounts, nil
+}
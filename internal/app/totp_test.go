@@ -2,12 +2,15 @@ package app_test
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/enckse/lockbox/internal/app"
+	"github.com/enckse/lockbox/internal/app/totp"
 	"github.com/enckse/lockbox/internal/config/store"
 	"github.com/enckse/lockbox/internal/kdbx"
 )
@@ -42,6 +45,7 @@ func fullTOTPSetup(t *testing.T, keep bool) *kdbx.Transaction {
 	store.SetArray("LOCKBOX_CREDENTIALS_PASSWORD", []string{"test"})
 	store.SetString("LOCKBOX_CREDENTIALS_PASSWORD_MODE", "plaintext")
 	store.SetInt64("LOCKBOX_TOTP_TIMEOUT", 1)
+	store.SetInt64("LOCKBOX_TOTP_NEXT_WITHIN", 0)
 	tr, err := kdbx.NewTransaction()
 	if err != nil {
 		t.Errorf("failed: %v", err)
@@ -122,6 +126,20 @@ func TestNewTOTPArguments(t *testing.T) {
 	if args.Mode != "seed" || args.Entry != "test" {
 		t.Error("invalid args")
 	}
+	args, _ = app.NewTOTPArguments([]string{"resync", "test", "123456"})
+	if args.Mode != "resync" || args.Entry != "test" || args.Code != "123456" {
+		t.Error("invalid args")
+	}
+	if _, err := app.NewTOTPArguments([]string{"resync", "test"}); err == nil || err.Error() != "resync requires an entry and a code" {
+		t.Errorf("invalid error: %v", err)
+	}
+	args, _ = app.NewTOTPArguments([]string{"import", "-dry-run", "test", "otpauth-migration://offline?data=a"})
+	if args.Mode != "import" || args.Entry != "test" || args.Migration != "otpauth-migration://offline?data=a" || !args.DryRun {
+		t.Error("invalid args")
+	}
+	if _, err := app.NewTOTPArguments([]string{"import", "test"}); err == nil || err.Error() != "import requires a group and a migration url" {
+		t.Errorf("invalid error: %v", err)
+	}
 }
 
 func TestDoErrors(t *testing.T) {
@@ -215,6 +233,37 @@ func TestOnce(t *testing.T) {
 	}
 }
 
+func TestOnceNext(t *testing.T) {
+	defer store.Clear()
+	setupTOTP(t)
+	args, _ := app.NewTOTPArguments([]string{"once", "test/test3/totp/otp"})
+	m, opts := newMock(t)
+	store.SetInt64("LOCKBOX_TOTP_NEXT_WITHIN", 30)
+	store.SetBool("LOCKBOX_FEATURE_COLOR", false)
+	if err := args.Do(opts); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	lines := strings.Split(m.buf.String(), "\n")
+	if len(lines) != 6 || !strings.HasSuffix(lines[4], " (next)") || !strings.HasSuffix(lines[0], ")") {
+		t.Errorf("invalid next: %s", m.buf.String())
+	}
+}
+
+func TestTOTPQR(t *testing.T) {
+	defer store.Clear()
+	setupTOTP(t)
+	m, opts := newMock(t)
+	store.SetBool("LOCKBOX_FEATURE_COLOR", false)
+	args, _ := app.NewTOTPArguments([]string{"qr", "test/test3/totp/otp"})
+	if err := args.Do(opts); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(m.buf.String(), "\n"), "\n")
+	if len(lines) != 29 || !strings.HasPrefix(lines[2], "████ ▄▄▄▄▄ █") {
+		t.Errorf("invalid qr: %s", m.buf.String())
+	}
+}
+
 func TestShow(t *testing.T) {
 	setupTOTP(t)
 	args, _ := app.NewTOTPArguments([]string{"show", "test/test3/totp/otp"})
@@ -228,34 +277,55 @@ func TestShow(t *testing.T) {
 }
 
 func TestParseWindows(t *testing.T) {
-	if _, err := app.ParseTimeWindow(); err.Error() != "invalid colorization rules for totp, none found" {
+	var buf bytes.Buffer
+	if _, err := app.ParseTimeWindow(&buf, 30); err.Error() != "invalid colorization rules for totp, none found" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := app.ParseTimeWindow(&buf, 30, " ", "2"); err.Error() != "invalid colorization rule found: 2" {
 		t.Errorf("invalid error: %v", err)
 	}
-	if _, err := app.ParseTimeWindow(" ", "2"); err.Error() != "invalid colorization rule found: 2" {
+	if _, err := app.ParseTimeWindow(&buf, 30, " 1:200"); err.Error() != "colorization rule exceeds the totp period (30s): 1:200" {
 		t.Errorf("invalid error: %v", err)
 	}
-	if _, err := app.ParseTimeWindow(" 1:200"); err.Error() != "invalid time found for colorization rule: 1:200" {
+	if _, err := app.ParseTimeWindow(&buf, 30, " 1:-1"); err.Error() != "invalid time found for colorization rule: 1:-1" {
 		t.Errorf("invalid error: %v", err)
 	}
-	if _, err := app.ParseTimeWindow(" 1:-1"); err.Error() != "invalid time found for colorization rule: 1:-1" {
+	if _, err := app.ParseTimeWindow(&buf, 30, " 200:1"); err.Error() != "invalid time found for colorization rule: 200:1" {
 		t.Errorf("invalid error: %v", err)
 	}
-	if _, err := app.ParseTimeWindow(" 200:1"); err.Error() != "invalid time found for colorization rule: 200:1" {
+	if _, err := app.ParseTimeWindow(&buf, 30, " 1:60"); err == nil || err.Error() != "colorization rule exceeds the totp period (30s): 1:60" {
 		t.Errorf("invalid error: %v", err)
 	}
-	if _, err := app.ParseTimeWindow(" -1:1"); err.Error() != "invalid time found for colorization rule: -1:1" {
+	if buf.String() != "" {
+		t.Errorf("invalid warning: %s", buf.String())
+	}
+	r, err := app.ParseTimeWindow(&buf, 30, "0:5", " 30:35", "50:59")
+	if err != nil || fmt.Sprintf("%v", r) != "[{0 5} {20 29}]" {
+		t.Errorf("invalid rules: %v %v", r, err)
+	}
+	if buf.String() != "warning: colorization rule 30:35 is in seconds of the minute (deprecated), using 0:5 of the totp period (30s)\nwarning: colorization rule 50:59 is in seconds of the minute (deprecated), using 20:29 of the totp period (30s)\n" {
+		t.Errorf("invalid warning: %s", buf.String())
+	}
+	buf.Reset()
+	if r, err := app.ParseTimeWindow(&buf, 30, "25:35"); err != nil || fmt.Sprintf("%v", r) != "[{25 30}]" || buf.String() == "" {
+		t.Errorf("invalid rules: %v %v", r, err)
+	}
+	if _, err := app.ParseTimeWindow(&buf, 90, " 30:90"); err != nil {
 		t.Errorf("invalid error: %v", err)
 	}
-	if _, err := app.ParseTimeWindow(" 2:1"); err.Error() != "invalid time found for colorization rule: 2:1" {
+	if _, err := app.ParseTimeWindow(&buf, 30, " -1:1"); err.Error() != "invalid time found for colorization rule: -1:1" {
 		t.Errorf("invalid error: %v", err)
 	}
-	if _, err := app.ParseTimeWindow("xxx:1"); err.Error() != "strconv.Atoi: parsing \"xxx\": invalid syntax" {
+	if _, err := app.ParseTimeWindow(&buf, 30, " 2:1"); err.Error() != "invalid time found for colorization rule: 2:1" {
 		t.Errorf("invalid error: %v", err)
 	}
-	if _, err := app.ParseTimeWindow(" 1:xxx"); err.Error() != "strconv.Atoi: parsing \"xxx\": invalid syntax" {
+	if _, err := app.ParseTimeWindow(&buf, 30, "xxx:1"); err.Error() != "strconv.Atoi: parsing \"xxx\": invalid syntax" {
 		t.Errorf("invalid error: %v", err)
 	}
-	if _, err := app.ParseTimeWindow("1:2", " 11:22"); err != nil {
+	if _, err := app.ParseTimeWindow(&buf, 30, " 1:xxx"); err.Error() != "strconv.Atoi: parsing \"xxx\": invalid syntax" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := app.ParseTimeWindow(&buf, 30, "1:2", " 11:22", "25:30"); err != nil {
 		t.Errorf("invalid error: %v", err)
 	}
 }
@@ -279,3 +349,199 @@ func TestTOTPListFilter(t *testing.T) {
 		t.Errorf("invalid list: %s", m.buf.String())
 	}
 }
+
+func TestHOTP(t *testing.T) {
+	defer store.Clear()
+	setupTOTP(t)
+	m, opts := newMock(t)
+	m.tx.Insert(kdbx.NewPath("test", "vpn"), map[string]string{"otp": "otpauth://hotp/lb:vpn?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&counter=0"})
+	for _, expect := range []string{"755224\n", "287082\n"} {
+		m.buf.Reset()
+		m.tx = fullTOTPSetup(t, true)
+		args, _ := app.NewTOTPArguments([]string{"minimal", "test/vpn/otp"})
+		if err := args.Do(opts); err != nil || m.buf.String() != expect {
+			t.Errorf("invalid code: %s %v", m.buf.String(), err)
+		}
+	}
+	m.buf.Reset()
+	m.tx = fullTOTPSetup(t, true)
+	args, _ := app.NewTOTPArguments([]string{"show", "test/vpn/otp"})
+	if err := args.Do(opts); err != nil || m.buf.String() != "test/vpn/otp (counter 2)\n    359152\n" {
+		t.Errorf("invalid code: %s %v", m.buf.String(), err)
+	}
+	m.buf.Reset()
+	m.tx = fullTOTPSetup(t, true)
+	args, _ = app.NewTOTPArguments([]string{"resync", "test/vpn/otp", "254676"})
+	if err := args.Do(opts); err != nil || m.buf.String() != "counter resynced: 6\n" {
+		t.Errorf("invalid resync: %s %v", m.buf.String(), err)
+	}
+	m.tx = fullTOTPSetup(t, true)
+	args, _ = app.NewTOTPArguments([]string{"resync", "test/vpn/otp", "254676"})
+	if err := args.Do(opts); err == nil || err.Error() != "code not found within 100 counters of 6" {
+		t.Errorf("invalid error: %v", err)
+	}
+	args, _ = app.NewTOTPArguments([]string{"resync", "test/test3/totp/otp", "254676"})
+	if err := args.Do(opts); err == nil || err.Error() != "'test/test3/totp/otp' is not a HOTP entry" {
+		t.Errorf("invalid error: %v", err)
+	}
+	m.tx = fullTOTPSetup(t, true)
+	store.SetBool("LOCKBOX_READONLY", true)
+	args, _ = app.NewTOTPArguments([]string{"minimal", "test/vpn/otp"})
+	if err := args.Do(opts); err == nil || err.Error() != "hotp counter can not be stored in read-only" {
+		t.Errorf("invalid error: %v", err)
+	}
+	m.buf.Reset()
+	args, _ = app.NewTOTPArguments([]string{"url", "test/vpn/otp"})
+	if err := args.Do(opts); err != nil || !strings.Contains(m.buf.String(), "counter:   6") {
+		t.Errorf("invalid url: %s %v", m.buf.String(), err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	defer store.Clear()
+	setupTOTP(t)
+	m, opts := newMock(t)
+	generator, _ := totp.New("5ae472abqdekjqykoyxk7hvc2leklq5n")
+	code, _ := generator.Code()
+	next, _ := generator.Next()
+	args, _ := app.NewTOTPArguments([]string{"verify", "test/test3/totp/otp", code[0:3] + " " + code[3:]})
+	if err := args.Do(opts); err != nil || m.buf.String() != "valid (current window)\n" {
+		t.Errorf("invalid verify: %s %v", m.buf.String(), err)
+	}
+	m.buf.Reset()
+	args, _ = app.NewTOTPArguments([]string{"verify", "test/test3/totp/otp", next})
+	if err := args.Do(opts); err != nil || m.buf.String() != "valid (+1 windows)\n" {
+		t.Errorf("invalid verify: %s %v", m.buf.String(), err)
+	}
+	store.SetInt64("LOCKBOX_TOTP_VERIFY_WINDOWS", 0)
+	if err := args.Do(opts); err == nil || err.Error() != "code is not valid within 0 windows" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := app.NewTOTPArguments([]string{"verify", "test/test3/totp/otp"}); err == nil || err.Error() != "verify requires an entry and a code" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestEncoders(t *testing.T) {
+	defer store.Clear()
+	setupTOTP(t)
+	m, opts := newMock(t)
+	tokens := map[string]string{
+		"steam":  "otpauth://totp/Steam:test?secret=63BEDWCQZKTQWPESARIERL5DTTQFCJTK&issuer=Steam&encoder=steam",
+		"sha512": "otpauth://totp/lb:sha512?secret=5ae472abqdekjqykoyxk7hvc2leklq5n&algorithm=SHA512&digits=8",
+	}
+	for name, token := range tokens {
+		m.tx.Insert(kdbx.NewPath("test", name), map[string]string{"otp": token})
+		m.tx = fullTOTPSetup(t, true)
+	}
+	copied := filepath.Join(t.TempDir(), "copied")
+	for name, token := range tokens {
+		store.SetBool("LOCKBOX_FEATURE_COLOR", false)
+		store.SetArray("LOCKBOX_CLIP_COPY", []string{"/bin/sh", "-c", "cat > " + copied})
+		store.SetInt64("LOCKBOX_CLIP_TIMEOUT", 0)
+		generator, _ := totp.New(token)
+		entry := "test/" + name + "/otp"
+		// NOTE: a code may roll over while running, check codes against the adjacent periods too
+		valid := func(code string) bool {
+			_, ok, err := generator.Verify(code, 1)
+			return ok && err == nil
+		}
+		for _, mode := range []string{"minimal", "once", "clip"} {
+			m.buf.Reset()
+			args, _ := app.NewTOTPArguments([]string{mode, entry})
+			if err := args.Do(opts); err != nil {
+				t.Errorf("invalid error: %v", err)
+			}
+			out := m.buf.String()
+			switch mode {
+			case "minimal":
+				if code, ok := strings.CutSuffix(out, "\n"); !ok || !valid(code) {
+					t.Errorf("invalid minimal: %s %s", name, out)
+				}
+			case "once":
+				_, code, _ := strings.Cut(out, entry+"\n    ")
+				if code, _, ok := strings.Cut(code, "\n"); !ok || !valid(code) {
+					t.Errorf("invalid once: %s %s", name, out)
+				}
+			case "clip":
+				if b, err := os.ReadFile(copied); err != nil || !valid(string(b)) {
+					t.Errorf("invalid copy: %s %s %v", name, string(b), err)
+				}
+			}
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	defer store.Clear()
+	setupTOTP(t)
+	m, opts := newMock(t)
+	copied := filepath.Join(t.TempDir(), "copied")
+	store.SetArray("LOCKBOX_CLIP_COPY", []string{"/bin/sh", "-c", "cat > " + copied})
+	store.SetInt64("LOCKBOX_CLIP_TIMEOUT", 0)
+	opts.Keys = func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("x2q")), nil
+	}
+	args, _ := app.NewTOTPArguments([]string{"watch"})
+	if err := args.Do(opts); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	out := m.buf.String()
+	if !strings.Contains(out, "1  test/test2/totp/otp  ") || !strings.Contains(out, "2  test/test3/totp/otp  ") || !strings.Contains(out, "-> no code for key: 'x'") || !strings.HasSuffix(out, "-> copied test/test3/totp/otp\n") {
+		t.Errorf("invalid watch: %s", out)
+	}
+	if b, err := os.ReadFile(copied); err != nil || len(b) != 6 {
+		t.Errorf("invalid copy: %s %v", string(b), err)
+	}
+	m.buf.Reset()
+	store.SetBool("LOCKBOX_FEATURE_CLIP", false)
+	args, _ = app.NewTOTPArguments([]string{"watch", "test3"})
+	if err := args.Do(opts); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	out = m.buf.String()
+	if strings.Contains(out, "test2") || !strings.Contains(out, "   test/test3/totp/otp  ") || !strings.HasSuffix(out, "exiting (timeout)\n") {
+		t.Errorf("invalid watch: %s", out)
+	}
+	args, _ = app.NewTOTPArguments([]string{"watch", "none"})
+	if err := args.Do(opts); err == nil || err.Error() != "no totp entries to watch" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := app.NewTOTPArguments([]string{"watch", "a", "b"}); err == nil || err.Error() != "watch takes only a filter (if any)" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	defer store.Clear()
+	setupTOTP(t)
+	m, opts := newMock(t)
+	const migration = "otpauth-migration://offline?data=Ci4KCkhlbGxvId6tvu8SEWFsaWNlQGV4YW1wbGUuY29tGgdFeGFtcGxlIAEoATACCigKFDEyMzQ1Njc4OTAxMjM0NTY3ODkwEgp0ZXN0Mzp0b3RwIAEoATACEAE="
+	args, _ := app.NewTOTPArguments([]string{"import", "-dry-run", "test", migration})
+	if err := args.Do(opts); err != nil || m.buf.String() != "test/Example/alice@example.com/otp (Example:alice@example.com)\nskipped (exists): test/test3/totp/otp\n" {
+		t.Errorf("invalid dry-run: %s %v", m.buf.String(), err)
+	}
+	m.buf.Reset()
+	m.tx = fullTOTPSetup(t, true)
+	args, _ = app.NewTOTPArguments([]string{"import", "test", migration})
+	if err := args.Do(opts); err != nil || m.buf.String() != "skipped (exists): test/test3/totp/otp\nimported: test/Example/alice@example.com/otp\n" {
+		t.Errorf("invalid import: %s %v", m.buf.String(), err)
+	}
+	m.buf.Reset()
+	m.tx = fullTOTPSetup(t, true)
+	args, _ = app.NewTOTPArguments([]string{"minimal", "test/Example/alice@example.com/otp"})
+	if err := args.Do(opts); err != nil || len(m.buf.String()) != 7 {
+		t.Errorf("invalid code: %s %v", m.buf.String(), err)
+	}
+	m.buf.Reset()
+	m.tx = fullTOTPSetup(t, true)
+	args, _ = app.NewTOTPArguments([]string{"import", "test", migration})
+	if err := args.Do(opts); err != nil || m.buf.String() != "skipped (exists): test/Example/alice@example.com/otp\nskipped (exists): test/test3/totp/otp\n" {
+		t.Errorf("invalid import: %s %v", m.buf.String(), err)
+	}
+	m.tx = fullTOTPSetup(t, true)
+	args, _ = app.NewTOTPArguments([]string{"import", "test", "otpauth://totp/a:b"})
+	if err := args.Do(opts); err == nil || err.Error() != "not a migration url, expected otpauth-migration://offline" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
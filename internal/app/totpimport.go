@@ -0,0 +1,92 @@
+// Package app handles importing authenticator migration exports
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/enckse/lockbox/internal/app/commands"
+	"github.com/enckse/lockbox/internal/app/totp"
+	"github.com/enckse/lockbox/internal/config"
+	"github.com/enckse/lockbox/internal/kdbx"
+)
+
+type importedAccount struct {
+	field string
+	url   string
+}
+
+func importTitle(account totp.MigrationAccount, idx int) []string {
+	var parts []string
+	for _, p := range []string{account.Issuer, account.Name} {
+		p = strings.TrimSpace(strings.ReplaceAll(p, "/", "-"))
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	if len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("account-%d", idx+1))
+	}
+	return parts
+}
+
+// importMigration will insert each account of a migration export as a totp entry under the group
+func (args *TOTPArguments) importMigration(opts TOTPOptions) error {
+	accounts, err := totp.ParseMigration(args.Migration)
+	if err != nil {
+		return err
+	}
+	check := config.EnvTOTPCheckOnInsert.Get()
+	t := opts.app.Transaction()
+	e, err := t.QueryCallback(kdbx.QueryOptions{Mode: kdbx.ListMode})
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]struct{})
+	for item, err := range e {
+		if err != nil {
+			return err
+		}
+		existing[item.Path] = struct{}{}
+	}
+	writer := opts.app.Writer()
+	var requests []kdbx.MoveRequest
+	var imported []importedAccount
+	for idx, account := range accounts {
+		path := kdbx.NewPath(append([]string{args.Entry}, importTitle(account, idx)...)...)
+		field := kdbx.NewPath(path, kdbx.OTPField)
+		if _, ok := existing[path]; ok {
+			fmt.Fprintf(writer, "skipped (exists): %s\n", field)
+			continue
+		}
+		if check {
+			generator, err := totp.New(account.URL)
+			if err != nil {
+				return err
+			}
+			if _, err := generator.Code(); err != nil {
+				return err
+			}
+		}
+		existing[path] = struct{}{}
+		if args.DryRun {
+			fmt.Fprintf(writer, "%s (%s)\n", field, account.Label())
+			continue
+		}
+		requests = append(requests, kdbx.MoveRequest{Source: &kdbx.Entity{Path: path, Values: kdbx.EntityValues{kdbx.OTPField: account.URL}}, Destination: path})
+		imported = append(imported, importedAccount{field: field, url: account.URL})
+	}
+	if len(requests) == 0 {
+		return nil
+	}
+	if err := t.Move(requests...); err != nil {
+		return err
+	}
+	for _, account := range imported {
+		if err := t.Record(fmt.Sprintf("%s %s", commands.TOTP, commands.TOTPImport), account.field, account.url); err != nil {
+			return err
+		}
+		fmt.Fprintf(writer, "imported: %s\n", account.field)
+	}
+	return nil
+}
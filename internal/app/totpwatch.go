@@ -0,0 +1,191 @@
+// Package app handles the multi-entry TOTP dashboard
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/enckse/lockbox/internal/app/commands"
+	"github.com/enckse/lockbox/internal/app/totp"
+	"github.com/enckse/lockbox/internal/config"
+	"github.com/enckse/lockbox/internal/kdbx"
+)
+
+const (
+	watchKeys = "123456789abcdefghijklmnoprstuvwxyz"
+	watchQuit = 'q'
+)
+
+type watchToken struct {
+	path      string
+	generator totp.Generator
+}
+
+func watchTokens(cmd CommandOptions, filter string) ([]watchToken, error) {
+	hasFilter, selector := createFilter(filter)
+	e, err := cmd.Transaction().QueryCallback(kdbx.QueryOptions{Mode: kdbx.ListMode, Values: kdbx.SecretValue})
+	if err != nil {
+		return nil, err
+	}
+	var tokens []watchToken
+	for item, err := range e {
+		if err != nil {
+			return nil, err
+		}
+		val, ok := item.Value(kdbx.OTPField)
+		if !ok {
+			continue
+		}
+		path := kdbx.NewPath(item.Path, kdbx.OTPField)
+		if hasFilter {
+			ok, err := selector(filter, path)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		generator, err := totp.New(val)
+		if err != nil {
+			return nil, err
+		}
+		// NOTE: HOTP codes would consume the counter on every refresh
+		if generator.IsHOTP() {
+			continue
+		}
+		if err := cmd.Transaction().Record(fmt.Sprintf("%s %s", commands.TOTP, commands.TOTPWatch), path, val); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, watchToken{path: path, generator: generator})
+	}
+	return tokens, nil
+}
+
+func readKeys(r io.Reader, keys chan<- byte) {
+	defer close(keys)
+	b := make([]byte, 1)
+	for {
+		if _, err := r.Read(b); err != nil {
+			return
+		}
+		keys <- b[0]
+	}
+}
+
+// watch will display a refreshing table of totp codes, a keypress copies a code
+func (args *TOTPArguments) watch(opts TOTPOptions) error {
+	tokens, err := watchTokens(opts.app, args.Entry)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return errors.New("no totp entries to watch")
+	}
+	// NOTE: tokens may have different periods, a window only has to fit the longest
+	period := 0
+	for _, token := range tokens {
+		period = max(period, token.generator.Period())
+	}
+	colorRules, err := colorWhenRules(period)
+	if err != nil {
+		return err
+	}
+	runFor, err := config.EnvTOTPTimeout.Get()
+	if err != nil {
+		return err
+	}
+	clipboard, clipErr := newClipboard()
+	canCopy := clipErr == nil
+	var keys chan byte
+	if canCopy {
+		r, err := opts.Keys()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		keys = make(chan byte)
+		go readKeys(r, keys)
+	}
+	width := 0
+	for _, token := range tokens {
+		width = max(width, len(token.path))
+	}
+	writer := opts.app.Writer()
+	allowColor := config.CanColor()
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	var running int64
+	var status string
+	for {
+		now := time.Now()
+		outputs := []string{now.Format("15:04:05"), ""}
+		for idx, token := range tokens {
+			code, err := token.generator.Code()
+			if err != nil {
+				return err
+			}
+			key := " "
+			if canCopy && idx < len(watchKeys) {
+				key = string(watchKeys[idx])
+			}
+			left := token.generator.Remaining(now)
+			countdown := fmt.Sprintf("(%02d)", left)
+			if allowColor {
+				for _, when := range colorRules {
+					if left < when.End && left >= when.Start {
+						countdown = fmt.Sprintf("\x1b[31m%s\x1b[39m", countdown)
+						break
+					}
+				}
+			}
+			outputs = append(outputs, fmt.Sprintf("%s  %-*s  %s  %s", key, width, token.path, code, countdown))
+		}
+		outputs = append(outputs, "")
+		if canCopy {
+			outputs = append(outputs, fmt.Sprintf("-> press a key to copy its code, '%c' to exit", watchQuit))
+		} else {
+			outputs = append(outputs, "-> CTRL+C to exit")
+		}
+		if status != "" {
+			outputs = append(outputs, status)
+		}
+		opts.Clear()
+		fmt.Fprintf(writer, "%s\n", strings.Join(outputs, "\n"))
+		select {
+		case key, ok := <-keys:
+			if !ok {
+				keys = nil
+				continue
+			}
+			if key == watchQuit {
+				return nil
+			}
+			idx := strings.IndexByte(watchKeys, key)
+			if idx < 0 || idx >= len(tokens) {
+				status = fmt.Sprintf("-> no code for key: %q", key)
+				continue
+			}
+			code, err := tokens[idx].generator.Code()
+			if err != nil {
+				return err
+			}
+			if err := clipboard.CopyTo(code); err != nil {
+				return fmt.Errorf("clipboard operation failed: %w", err)
+			}
+			status = fmt.Sprintf("-> copied %s", tokens[idx].path)
+			if clipboard.MaxTime > 0 {
+				status = fmt.Sprintf("%s (clears in %d seconds)", status, clipboard.MaxTime)
+			}
+		case <-ticker.C:
+			running++
+			if running > runFor {
+				fmt.Fprint(writer, "exiting (timeout)\n")
+				return nil
+			}
+		}
+	}
+}
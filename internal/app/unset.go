@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/enckse/lockbox/internal/app/commands"
 	"github.com/enckse/lockbox/internal/kdbx"
 )
 
@@ -51,7 +52,7 @@ func Unset(cmd CommandOptions) error {
 		if err := t.Insert(dir, vals); err != nil {
 			return err
 		}
-		return nil
+		return t.Record(commands.Unset, entry, "")
 	}
 	return fmt.Errorf("unable to unset: %s", entry)
 }
@@ -0,0 +1,387 @@
+// Package auditlog handles an append-only, HMAC chained, log of operations
+package auditlog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	macInfo   = "lockbox audit log mac"
+	valueInfo = "lockbox audit log value"
+	keySize   = 32
+	// lockSuffix is the (sidecar) lock file, the log itself is replaced when re-keyed
+	lockSuffix = ".lock"
+	// headSuffix is the (sidecar) signed head of the log, the record count and last MAC
+	headSuffix = ".head"
+	tailChunk  = 4096
+	// TimeFormat is the record timestamp format
+	TimeFormat = time.RFC3339
+)
+
+type (
+	// Key is the (derived) keying material for the log
+	Key struct {
+		mac   []byte
+		value []byte
+	}
+	// Record is a single logged operation
+	Record struct {
+		Time    string `json:"time"`
+		Command string `json:"command"`
+		Path    string `json:"path"`
+		Hash    string `json:"hash,omitempty"`
+		MAC     string `json:"mac"`
+	}
+	head struct {
+		Count int    `json:"count"`
+		MAC   string `json:"mac"`
+		Sig   string `json:"sig"`
+	}
+	// Staged is a re-signed log waiting to replace the current log (the log is locked until
+	// committed or discarded)
+	Staged struct {
+		file   string
+		tmp    string
+		head   string
+		unlock func()
+	}
+)
+
+// NewKey will derive the log key from the database credentials
+func NewKey(password string, keyFile []byte) (Key, error) {
+	secret := append([]byte(password), keyFile...)
+	defer clear(secret)
+	if len(secret) == 0 {
+		return Key{}, errors.New("no credentials to key audit log")
+	}
+	mac, err := hkdf.Key(sha256.New, secret, nil, macInfo, keySize)
+	if err != nil {
+		return Key{}, err
+	}
+	value, err := hkdf.Key(sha256.New, secret, nil, valueInfo, keySize)
+	if err != nil {
+		return Key{}, err
+	}
+	return Key{mac: mac, value: value}, nil
+}
+
+func (k Key) hash(value string) string {
+	if value == "" {
+		return ""
+	}
+	h := hmac.New(sha256.New, k.value)
+	h.Write([]byte(value))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (k Key) sign(previous string, r Record) string {
+	h := hmac.New(sha256.New, k.mac)
+	for _, field := range []string{previous, r.Time, r.Command, r.Path, r.Hash} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (k Key) signHead(count int, mac string) string {
+	h := hmac.New(sha256.New, k.mac)
+	for _, field := range []string{headSuffix, strconv.Itoa(count), mac} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// readHead will read the head of the log (empty when there is no head)
+func readHead(file string, key Key) (head, error) {
+	b, err := os.ReadFile(file + headSuffix)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return head{}, nil
+		}
+		return head{}, err
+	}
+	var h head
+	if err := json.Unmarshal(b, &h); err != nil {
+		return head{}, fmt.Errorf("invalid audit log head: %w", err)
+	}
+	if !hmac.Equal([]byte(key.signHead(h.Count, h.MAC)), []byte(h.Sig)) {
+		return head{}, errors.New("audit log head tampered")
+	}
+	return h, nil
+}
+
+// stageHead will write a signed head to a temporary file (to be renamed over the head)
+func stageHead(file string, key Key, count int, mac string) (string, error) {
+	b, err := json.Marshal(head{Count: count, MAC: mac, Sig: key.signHead(count, mac)})
+	if err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+headSuffix)
+	if err != nil {
+		return "", err
+	}
+	_, err = tmp.Write(append(b, '\n'))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+func writeHead(file string, key Key, count int, mac string) error {
+	tmp, err := stageHead(file, key, count, mac)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, file+headSuffix); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// Read will read all records from the log (without verification)
+func Read(file string) ([]Record, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			return nil, fmt.Errorf("invalid audit log record %d: %w", len(records)+1, err)
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// Verify will check the chain of records (and that the log ends at the signed head), returning
+// the number of records
+func Verify(file string, key Key) (int, error) {
+	unlock, err := lock(file)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	return verify(file, key)
+}
+
+func verify(file string, key Key) (int, error) {
+	records, err := Read(file)
+	if err != nil {
+		return 0, err
+	}
+	previous := ""
+	for idx, r := range records {
+		if !hmac.Equal([]byte(key.sign(previous, r)), []byte(r.MAC)) {
+			return 0, fmt.Errorf("audit log tampered at record %d", idx+1)
+		}
+		previous = r.MAC
+	}
+	h, err := readHead(file, key)
+	if err != nil {
+		return 0, err
+	}
+	if h.Count != len(records) || h.MAC != previous {
+		return 0, fmt.Errorf("audit log head mismatch, expected %d records (have %d)", h.Count, len(records))
+	}
+	return len(records), nil
+}
+
+// lock will take an exclusive lock on the log, returning the unlock function
+func lock(file string) (func(), error) {
+	f, err := os.OpenFile(file+lockSuffix, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// lastMAC will read (backwards from the end) only the last record of the log for its MAC
+func lastMAC(f *os.File) (string, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	end := info.Size()
+	var tail []byte
+	for end > 0 {
+		size := min(end, tailChunk)
+		end -= size
+		chunk := make([]byte, size)
+		if _, err := f.ReadAt(chunk, end); err != nil {
+			return "", err
+		}
+		tail = append(chunk, tail...)
+		trimmed := bytes.TrimSpace(tail)
+		idx := bytes.LastIndexByte(trimmed, '\n')
+		if len(trimmed) == 0 || (idx < 0 && end > 0) {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal(bytes.TrimSpace(trimmed[idx+1:]), &r); err != nil {
+			return "", fmt.Errorf("invalid audit log record: %w", err)
+		}
+		return r.MAC, nil
+	}
+	return "", nil
+}
+
+// Append will add an operation to the end of the log (the value is only stored as a keyed hash) and
+// move the signed head to it
+func Append(file string, key Key, command, path, value string) error {
+	unlock, err := lock(file)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return err
+	}
+	previous, err := lastMAC(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	h, err := readHead(file, key)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if h.MAC != previous {
+		f.Close()
+		return errors.New("audit log head mismatch, the log does not end at the head")
+	}
+	r := Record{Time: time.Now().UTC().Format(TimeFormat), Command: command, Path: path, Hash: key.hash(value)}
+	r.MAC = key.sign(previous, r)
+	b, err := json.Marshal(r)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return writeHead(file, key, h.Count+1, r.MAC)
+}
+
+// ReKey will verify the log with the current key and re-sign it with a new key (value hashes are
+// kept) into a temporary file, the log stays locked (and unchanged) until committed or discarded
+func ReKey(file string, current, next Key) (*Staged, error) {
+	unlock, err := lock(file)
+	if err != nil {
+		return nil, err
+	}
+	staged, err := stage(file, current, next)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	staged.unlock = unlock
+	return staged, nil
+}
+
+func stage(file string, current, next Key) (*Staged, error) {
+	if _, err := verify(file, current); err != nil {
+		return nil, err
+	}
+	records, err := Read(file)
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file))
+	if err != nil {
+		return nil, err
+	}
+	staged := &Staged{file: file, tmp: tmp.Name()}
+	previous := ""
+	for _, r := range records {
+		r.MAC = next.sign(previous, r)
+		previous = r.MAC
+		b, err := json.Marshal(r)
+		if err == nil {
+			_, err = tmp.Write(append(b, '\n'))
+		}
+		if err != nil {
+			tmp.Close()
+			os.Remove(staged.tmp)
+			return nil, err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(staged.tmp)
+		return nil, err
+	}
+	staged.head, err = stageHead(file, next, len(records), previous)
+	if err != nil {
+		os.Remove(staged.tmp)
+		return nil, err
+	}
+	return staged, nil
+}
+
+// Commit will replace the log (and head) with the re-signed log
+func (s *Staged) Commit() error {
+	defer s.unlock()
+	if err := os.Rename(s.tmp, s.file); err != nil {
+		os.Remove(s.tmp)
+		os.Remove(s.head)
+		return err
+	}
+	if err := os.Rename(s.head, s.file+headSuffix); err != nil {
+		os.Remove(s.head)
+		return err
+	}
+	return nil
+}
+
+// Discard will remove the re-signed log (the current log is kept)
+func (s *Staged) Discard() {
+	defer s.unlock()
+	os.Remove(s.tmp)
+	os.Remove(s.head)
+}
@@ -0,0 +1,179 @@
+package auditlog_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/enckse/lockbox/internal/auditlog"
+)
+
+func newLog(t *testing.T) (string, auditlog.Key) {
+	file := filepath.Join(t.TempDir(), "audit.log")
+	key, err := auditlog.NewKey("test", nil)
+	if err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	for _, path := range []string{"a/b", "a/c", "d/e"} {
+		if err := auditlog.Append(file, key, "show", path, "secret"); err != nil {
+			t.Errorf("invalid error: %v", err)
+		}
+	}
+	return file, key
+}
+
+func TestNewKey(t *testing.T) {
+	if _, err := auditlog.NewKey("", nil); err == nil || err.Error() != "no credentials to key audit log" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := auditlog.NewKey("", []byte("key")); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestAppendVerify(t *testing.T) {
+	file, key := newLog(t)
+	count, err := auditlog.Verify(file, key)
+	if err != nil || count != 3 {
+		t.Errorf("invalid verify: %d %v", count, err)
+	}
+	records, err := auditlog.Read(file)
+	if err != nil || len(records) != 3 || records[1].Path != "a/c" || records[1].Command != "show" {
+		t.Errorf("invalid records: %v %v", records, err)
+	}
+	if records[0].Hash != records[1].Hash || records[0].Hash == "" || records[0].MAC == records[1].MAC {
+		t.Errorf("invalid records: %v", records)
+	}
+	b, _ := os.ReadFile(file)
+	if strings.Contains(string(b), "secret") {
+		t.Error("value leaked")
+	}
+	other, _ := auditlog.NewKey("other", nil)
+	if _, err := auditlog.Verify(file, other); err == nil || err.Error() != "audit log tampered at record 1" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := auditlog.Verify(filepath.Join(t.TempDir(), "none"), key); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestTampered(t *testing.T) {
+	file, key := newLog(t)
+	b, _ := os.ReadFile(file)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	os.WriteFile(file, []byte(strings.Replace(string(b), "a/c", "a/x", 1)), 0o600)
+	if _, err := auditlog.Verify(file, key); err == nil || err.Error() != "audit log tampered at record 2" {
+		t.Errorf("invalid error: %v", err)
+	}
+	os.WriteFile(file, []byte(lines[0]+"\n"+lines[2]+"\n"), 0o600)
+	if _, err := auditlog.Verify(file, key); err == nil || err.Error() != "audit log tampered at record 2" {
+		t.Errorf("invalid error: %v", err)
+	}
+	os.WriteFile(file, []byte(lines[1]+"\n"+lines[2]+"\n"), 0o600)
+	if _, err := auditlog.Verify(file, key); err == nil || err.Error() != "audit log tampered at record 1" {
+		t.Errorf("invalid error: %v", err)
+	}
+	os.WriteFile(file, []byte(lines[0]+"\n"+lines[1]+"\n"), 0o600)
+	if _, err := auditlog.Verify(file, key); err == nil || err.Error() != "audit log head mismatch, expected 3 records (have 2)" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := auditlog.Append(file, key, "show", "a/b", ""); err == nil || err.Error() != "audit log head mismatch, the log does not end at the head" {
+		t.Errorf("invalid error: %v", err)
+	}
+	os.Remove(file)
+	if _, err := auditlog.Verify(file, key); err == nil || err.Error() != "audit log head mismatch, expected 3 records (have 0)" {
+		t.Errorf("invalid error: %v", err)
+	}
+	os.WriteFile(file, b, 0o600)
+	if count, err := auditlog.Verify(file, key); err != nil || count != 3 {
+		t.Errorf("invalid verify: %d %v", count, err)
+	}
+	head, _ := os.ReadFile(file + ".head")
+	os.WriteFile(file+".head", []byte(strings.Replace(string(head), `"count":3`, `"count":2`, 1)), 0o600)
+	if _, err := auditlog.Verify(file, key); err == nil || err.Error() != "audit log head tampered" {
+		t.Errorf("invalid error: %v", err)
+	}
+	os.Remove(file + ".head")
+	if _, err := auditlog.Verify(file, key); err == nil || err.Error() != "audit log head mismatch, expected 0 records (have 3)" {
+		t.Errorf("invalid error: %v", err)
+	}
+	os.WriteFile(file, []byte("{\n"), 0o600)
+	if _, err := auditlog.Verify(file, key); err == nil || !strings.HasPrefix(err.Error(), "invalid audit log record 1:") {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestAppendConcurrent(t *testing.T) {
+	file, key := newLog(t)
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := auditlog.Append(file, key, "show", "x/y", "value"); err != nil {
+				t.Errorf("invalid error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	for _, path := range []string{strings.Repeat("a", 10000), "b"} {
+		if err := auditlog.Append(file, key, "show", path, ""); err != nil {
+			t.Errorf("invalid error: %v", err)
+		}
+	}
+	if count, err := auditlog.Verify(file, key); err != nil || count != 25 {
+		t.Errorf("invalid verify: %d %v", count, err)
+	}
+}
+
+func TestReKey(t *testing.T) {
+	file, key := newLog(t)
+	next, _ := auditlog.NewKey("next", nil)
+	if _, err := auditlog.ReKey(file, next, key); err == nil || err.Error() != "audit log tampered at record 1" {
+		t.Errorf("invalid error: %v", err)
+	}
+	staged, err := auditlog.ReKey(file, key, next)
+	if err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	verified := make(chan struct{})
+	go func() {
+		auditlog.Verify(file, key)
+		close(verified)
+	}()
+	select {
+	case <-verified:
+		t.Error("verify should wait for the staged log")
+	case <-time.After(100 * time.Millisecond):
+	}
+	staged.Discard()
+	<-verified
+	if count, err := auditlog.Verify(file, key); err != nil || count != 3 {
+		t.Errorf("invalid verify: %d %v", count, err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(file)); len(entries) != 3 {
+		t.Errorf("staged log not removed: %v", entries)
+	}
+	staged, err = auditlog.ReKey(file, key, next)
+	if err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := staged.Commit(); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := auditlog.Verify(file, key); err == nil {
+		t.Error("old key should fail")
+	}
+	if count, err := auditlog.Verify(file, next); err != nil || count != 3 {
+		t.Errorf("invalid verify: %d %v", count, err)
+	}
+	if err := auditlog.Append(file, next, "insert", "a/b", ""); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if count, err := auditlog.Verify(file, next); err != nil || count != 4 {
+		t.Errorf("invalid verify: %d %v", count, err)
+	}
+}
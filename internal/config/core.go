@@ -23,6 +23,11 @@ const (
 	jsonCategory         = "JSON_"
 	credsCategory        = "CREDENTIALS_"
 	defaultCategory      = "DEFAULTS_"
+	agentCategory        = "AGENT_"
+	keyringCategory      = "KEYRING_"
+	rotationCategory     = "ROTATION_"
+	policyCategory       = "POLICY_"
+	auditCategory        = "AUDIT_"
 	environmentPrefix    = "LOCKBOX_"
 	commandArgsExample   = "[cmd args...]"
 	fileExample          = "<file>"
@@ -34,6 +39,14 @@ const (
 	arrayDelimiter     = " "
 	// TimeWindowSpan indicates the delineation between start -> end (start:end)
 	TimeWindowSpan = ":"
+	// DetectClipBackend will detect the platform clipboard (falling back to OSC 52 without a display)
+	DetectClipBackend = "detect"
+	// OSC52ClipBackend will copy via the OSC 52 terminal escape sequence
+	OSC52ClipBackend = "osc52"
+	// ClipboardSelection is the (default) system clipboard selection
+	ClipboardSelection = "clipboard"
+	// PrimarySelection is the (X11/wayland) primary selection
+	PrimarySelection = "primary"
 	// NoColorFlag is the common color disable flag
 	NoColorFlag = "NO_COLOR"
 )
@@ -58,8 +71,8 @@ var (
 	YesValue = strconv.FormatBool(true)
 	// NoValue is the string variant of 'No' (or false) items
 	NoValue = strconv.FormatBool(false)
-	// TOTPDefaultColorWindow is the default coloring rules for totp
-	TOTPDefaultColorWindow = []TimeWindow{{Start: 0, End: 5}, {Start: 30, End: 35}}
+	// TOTPDefaultColorWindow is the default coloring rules for totp (seconds remaining in the period)
+	TOTPDefaultColorWindow = []TimeWindow{{Start: 0, End: 5}}
 	// TOTPDefaultBetween is the default color window as a string
 	TOTPDefaultBetween = func() []string {
 		var results []string
@@ -48,6 +48,11 @@ type (
 	EnvironmentArray struct {
 		environmentStrings
 	}
+	// EnvironmentTable is a table (string keys to string values) variable
+	EnvironmentTable struct {
+		environmentBase
+		allowed string
+	}
 	metaData struct {
 		value     string
 		tomlType  tomlType
@@ -107,6 +112,12 @@ func (e EnvironmentArray) Get() []string {
 	})
 }
 
+// Get will retrieve the table (nil when not set)
+func (e EnvironmentTable) Get() map[string]string {
+	val, _ := store.GetTable(e.Key())
+	return val
+}
+
 func stringsGet[T string | []string](e environmentStrings, getter func(string) (T, bool), conv func(string) T) T {
 	val, ok := getter(e.Key())
 	if !ok {
@@ -188,6 +199,16 @@ func (e EnvironmentInt) display() metaData {
 	}
 }
 
+func (e EnvironmentTable) display() metaData {
+	return metaData{
+		value:     "",
+		allowed:   []string{e.allowed},
+		tomlType:  tomlTable,
+		tomlValue: "{}",
+		canExpand: false,
+	}
+}
+
 func (e EnvironmentFormatter) display() metaData {
 	return metaData{
 		value:     strings.ReplaceAll(strings.ReplaceAll(EnvTOTPFormat.Get("%s"), "%25s", "%s"), "&", " \\\n           &"),
@@ -4,8 +4,18 @@ package config
 import (
 	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/enckse/lockbox/internal/agent"
+	"github.com/enckse/lockbox/internal/keyring"
+	"github.com/enckse/lockbox/internal/memory"
+	"github.com/enckse/lockbox/internal/platform/pinentry"
+	"github.com/enckse/lockbox/internal/platform/tty"
+	"github.com/enckse/lockbox/internal/shamir"
 )
 
 type (
@@ -19,12 +29,32 @@ type (
 	}
 )
 
+const (
+	askPassEnv = "SSH_ASKPASS"
+	keyPrompt  = "please enter password: "
+)
+
+// NOTE: prompted keys are kept in locked memory (and zeroed when forgotten)
+var promptedKeys = make(map[string]*memory.Buffer)
+
 const (
 	plainKeyMode KeyModeType = "plaintext"
 	noKeyMode    KeyModeType = "none"
 	// IgnoreKeyMode will ignore the value set in the key (acts like no key)
 	IgnoreKeyMode  KeyModeType = "ignore"
 	commandKeyMode KeyModeType = "command"
+	// AgentKeyMode will ask the agent for a cached key before running the key command
+	AgentKeyMode KeyModeType = "agent"
+	// AskKeyMode will prompt for the key on the controlling terminal
+	AskKeyMode KeyModeType = "ask"
+	// AskPassKeyMode will run an askpass helper (e.g. SSH_ASKPASS) to prompt for the key
+	AskPassKeyMode KeyModeType = "askpass"
+	// PinentryKeyMode will use a pinentry program (assuan protocol) to prompt for the key
+	PinentryKeyMode KeyModeType = "pinentry"
+	// ShardsKeyMode will prompt for shamir shards (on the controlling terminal) to reconstruct the key
+	ShardsKeyMode KeyModeType = "shards"
+	// KeyringKeyMode will read a cached key from the kernel keyring before running the key command
+	KeyringKeyMode KeyModeType = "keyring"
 	// DefaultKeyMode is the default operating keymode if NOT set
 	DefaultKeyMode = commandKeyMode
 )
@@ -36,12 +66,15 @@ func NewKey(defaultKeyModeType KeyModeType) (Key, error) {
 		keyMode = string(defaultKeyModeType)
 	}
 	requireEmptyKey := false
+	allowEmptyKey := false
 	switch keyMode {
 	case string(IgnoreKeyMode):
 		return Key{mode: IgnoreKeyMode, inputKey: []string{}, valid: true}, nil
-	case string(noKeyMode):
+	case string(noKeyMode), string(AskKeyMode), string(ShardsKeyMode):
 		requireEmptyKey = true
-	case string(commandKeyMode), string(plainKeyMode):
+	case string(AskPassKeyMode), string(PinentryKeyMode):
+		allowEmptyKey = true
+	case string(commandKeyMode), string(plainKeyMode), string(AgentKeyMode), string(KeyringKeyMode):
 	default:
 		return Key{}, fmt.Errorf("unknown key mode: %s", keyMode)
 	}
@@ -56,16 +89,26 @@ func NewKey(defaultKeyModeType KeyModeType) (Key, error) {
 		if !isEmpty {
 			return Key{}, errors.New("key can NOT be set in this key mode")
 		}
+		if keyMode == string(AskKeyMode) || keyMode == string(ShardsKeyMode) {
+			return Key{mode: KeyModeType(keyMode), inputKey: []string{}, valid: true}, nil
+		}
 	} else {
 		if isEmpty {
-			return Key{}, errors.New("key MUST be set in this key mode")
+			if !allowEmptyKey {
+				return Key{}, errors.New("key MUST be set in this key mode")
+			}
+			useKey = []string{}
 		}
 	}
 	return Key{mode: KeyModeType(keyMode), inputKey: useKey, valid: true}, nil
 }
 
 func (k Key) empty() bool {
-	return k.valid && len(k.inputKey) == 0
+	return k.valid && len(k.inputKey) == 0 && !k.interactive()
+}
+
+func (k Key) interactive() bool {
+	return k.mode == AskKeyMode || k.mode == AskPassKeyMode || k.mode == PinentryKeyMode || k.mode == ShardsKeyMode
 }
 
 // Read will read the key as configured by the mode
@@ -82,20 +125,33 @@ func (k Key) Read() (string, error) {
 	}
 	switch k.mode {
 	case commandKeyMode:
-		exe := k.inputKey[0]
-		var args []string
-		for idx, k := range k.inputKey {
-			if idx == 0 {
-				continue
-			}
-			args = append(args, k)
-		}
-		cmd := exec.Command(exe, args...)
-		b, err := cmd.Output()
+		b, err := k.command()
 		if err != nil {
-			return "", fmt.Errorf("key command failed: %w", err)
+			return "", err
 		}
 		useKey = string(b)
+	case AskKeyMode, AskPassKeyMode, PinentryKeyMode, ShardsKeyMode:
+		return k.prompt()
+	case AgentKeyMode:
+		socket := AgentSocket()
+		id := EnvStore.Get()
+		return k.cached(func() ([]byte, error) {
+			return agent.Get(socket, id)
+		}, func(b []byte) error {
+			return agent.Set(socket, id, b)
+		})
+	case KeyringKeyMode:
+		timeout, err := EnvKeyringTimeout.Get()
+		if err != nil {
+			return "", err
+		}
+		ring := keyring.Type(EnvKeyringType.Get())
+		id := EnvStore.Get()
+		return k.cached(func() ([]byte, error) {
+			return keyring.Get(ring, id)
+		}, func(b []byte) error {
+			return keyring.Set(ring, id, b, time.Duration(timeout)*time.Second)
+		})
 	}
 	key := strings.TrimSpace(useKey)
 	if key == "" {
@@ -103,3 +159,145 @@ func (k Key) Read() (string, error) {
 	}
 	return key, nil
 }
+
+func (k Key) cached(get func() ([]byte, error), set func([]byte) error) (string, error) {
+	if b, err := get(); err == nil {
+		return string(b), nil
+	}
+	b, err := k.command()
+	if err != nil {
+		return "", err
+	}
+	key := strings.TrimSpace(string(b))
+	if key == "" {
+		return "", errors.New("key is empty")
+	}
+	// NOTE: caching is optional, failing to cache is not fatal
+	set([]byte(key))
+	return key, nil
+}
+
+func (k Key) prompt() (string, error) {
+	// NOTE: a process may unlock the store multiple times, only prompt once
+	id := strings.Join(append([]string{string(k.mode)}, k.inputKey...), " ")
+	if buffer, ok := promptedKeys[id]; ok {
+		b := buffer.Bytes()
+		defer clear(b)
+		return string(b), nil
+	}
+	var key string
+	switch k.mode {
+	case AskKeyMode:
+		read, err := tty.ReadSecret(keyPrompt)
+		if err != nil {
+			return "", err
+		}
+		key = read
+	case ShardsKeyMode:
+		b, err := ReadShards(tty.ReadSecret)
+		if err != nil {
+			return "", err
+		}
+		key = strings.TrimSpace(string(b))
+		clear(b)
+	case AskPassKeyMode:
+		helper := k.inputKey
+		if len(helper) == 0 {
+			env := strings.TrimSpace(os.Getenv(askPassEnv))
+			if env == "" {
+				return "", fmt.Errorf("no askpass helper set and %s is not set", askPassEnv)
+			}
+			helper = []string{env}
+		}
+		b, err := runCommand(append(helper, keyPrompt))
+		if err != nil {
+			return "", err
+		}
+		key = strings.TrimSpace(string(b))
+	case PinentryKeyMode:
+		var options []string
+		if term := os.Getenv("GPG_TTY"); term != "" {
+			options = append(options, fmt.Sprintf("ttyname=%s", term))
+			if t := os.Getenv("TERM"); t != "" {
+				options = append(options, fmt.Sprintf("ttytype=%s", t))
+			}
+		}
+		pin, err := pinentry.GetPIN(k.inputKey, pinentry.Request{
+			Title:       "lockbox",
+			Description: fmt.Sprintf("Please enter the password for %s", filepath.Base(EnvStore.Get())),
+			Prompt:      "Password:",
+			Options:     options,
+		})
+		if err != nil {
+			return "", err
+		}
+		key = strings.TrimSpace(pin)
+	}
+	if key == "" {
+		return "", errors.New("key is empty")
+	}
+	buffer, err := memory.New([]byte(key))
+	if err != nil {
+		return "", err
+	}
+	promptedKeys[id] = buffer
+	return key, nil
+}
+
+// ForgetPromptedKeys will zero (and release) any keys prompted for by this process
+func ForgetPromptedKeys() {
+	for id, buffer := range promptedKeys {
+		buffer.Destroy()
+		delete(promptedKeys, id)
+	}
+}
+
+func (k Key) command() ([]byte, error) {
+	return runCommand(k.inputKey)
+}
+
+func runCommand(command []string) ([]byte, error) {
+	exe := command[0]
+	var args []string
+	for idx, k := range command {
+		if idx == 0 {
+			continue
+		}
+		args = append(args, k)
+	}
+	cmd := exec.Command(exe, args...)
+	b, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("key command failed: %w", err)
+	}
+	return b, nil
+}
+
+// AgentSocket gets the configured (or detected) agent socket
+func AgentSocket() string {
+	socket := EnvAgentSocket.Get()
+	if socket == "" {
+		return agent.DefaultSocket()
+	}
+	return socket
+}
+
+// ReadShards will read shamir shards (until the threshold is met) and reconstruct the secret
+func ReadShards(read func(string) (string, error)) ([]byte, error) {
+	var shares []shamir.Share
+	for {
+		text, err := read(fmt.Sprintf("please enter shard %d: ", len(shares)+1))
+		if err != nil {
+			return nil, err
+		}
+		share, err := shamir.Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+		if len(shares) >= share.Threshold {
+			break
+		}
+	}
+	return shamir.Combine(shares)
+}
@@ -1,11 +1,18 @@
 package config_test
 
 import (
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/enckse/lockbox/internal/agent"
 	"github.com/enckse/lockbox/internal/config"
 	"github.com/enckse/lockbox/internal/config/store"
+	"github.com/enckse/lockbox/internal/keyring"
+	"github.com/enckse/lockbox/internal/shamir"
 )
 
 func TestDefaultKey(t *testing.T) {
@@ -125,3 +132,209 @@ func TestCommandKey(t *testing.T) {
 		t.Errorf("invalid error: %v", err)
 	}
 }
+
+func TestAgentKey(t *testing.T) {
+	store.Clear()
+	defer store.Clear()
+	store.SetString("LOCKBOX_CREDENTIALS_PASSWORD_MODE", "agent")
+	store.SetString("LOCKBOX_AGENT_SOCKET", filepath.Join(t.TempDir(), "agent.sock"))
+	store.SetString("LOCKBOX_STORE", "test.kdbx")
+	store.SetArray("LOCKBOX_CREDENTIALS_PASSWORD", []string{"thisisagarbagekey"})
+	k, err := config.NewKey(config.IgnoreKeyMode)
+	if err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err = k.Read(); err == nil || !strings.HasPrefix(err.Error(), "key command failed:") {
+		t.Errorf("invalid error: %v", err)
+	}
+	store.SetArray("LOCKBOX_CREDENTIALS_PASSWORD", []string{"/bin/echo", "test"})
+	k, err = config.NewKey(config.IgnoreKeyMode)
+	if err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	val, err := k.Read()
+	if err != nil || val != "test" {
+		t.Errorf("invalid read: %s %v", val, err)
+	}
+	s, err := agent.NewServer(config.AgentSocket(), time.Minute, time.Minute)
+	if err != nil {
+		t.Fatalf("invalid error: %v", err)
+	}
+	defer s.Close()
+	go s.Run()
+	if val, err := k.Read(); err != nil || val != "test" {
+		t.Errorf("invalid read: %s %v", val, err)
+	}
+	store.SetArray("LOCKBOX_CREDENTIALS_PASSWORD", []string{"thisisagarbagekey"})
+	k, err = config.NewKey(config.IgnoreKeyMode)
+	if err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if val, err := k.Read(); err != nil || val != "test" {
+		t.Errorf("invalid read: %s %v", val, err)
+	}
+}
+
+func TestAgentSocket(t *testing.T) {
+	store.Clear()
+	defer store.Clear()
+	t.Setenv("XDG_RUNTIME_DIR", "xdg")
+	if s := config.AgentSocket(); s != filepath.Join("xdg", "lockbox", "agent.sock") {
+		t.Errorf("invalid socket: %s", s)
+	}
+	store.SetString("LOCKBOX_AGENT_SOCKET", "abc")
+	if s := config.AgentSocket(); s != "abc" {
+		t.Errorf("invalid socket: %s", s)
+	}
+}
+
+func TestKeyringKey(t *testing.T) {
+	store.Clear()
+	defer store.Clear()
+	store.SetString("LOCKBOX_CREDENTIALS_PASSWORD_MODE", "keyring")
+	store.SetString("LOCKBOX_KEYRING_TYPE", "invalid")
+	store.SetString("LOCKBOX_STORE", t.TempDir())
+	store.SetArray("LOCKBOX_CREDENTIALS_PASSWORD", []string{"/bin/echo", "test"})
+	k, err := config.NewKey(config.IgnoreKeyMode)
+	if err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if val, err := k.Read(); err != nil || val != "test" {
+		t.Errorf("invalid read: %s %v", val, err)
+	}
+	store.SetInt64("LOCKBOX_KEYRING_TIMEOUT", -1)
+	if _, err := k.Read(); err == nil || err.Error() != "keyring timeout must be > 0" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if runtime.GOOS != "linux" {
+		return
+	}
+	store.SetInt64("LOCKBOX_KEYRING_TIMEOUT", 60)
+	store.SetString("LOCKBOX_KEYRING_TYPE", "session")
+	if val, err := k.Read(); err != nil || val != "test" {
+		t.Errorf("invalid read: %s %v", val, err)
+	}
+	defer keyring.Remove(keyring.SessionKeyring, config.EnvStore.Get())
+	store.SetArray("LOCKBOX_CREDENTIALS_PASSWORD", []string{"thisisagarbagekey"})
+	k, err = config.NewKey(config.IgnoreKeyMode)
+	if err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if val, err := k.Read(); err != nil || val != "test" {
+		t.Errorf("invalid read: %s %v", val, err)
+	}
+}
+
+func TestAskKey(t *testing.T) {
+	store.Clear()
+	defer store.Clear()
+	store.SetString("LOCKBOX_CREDENTIALS_PASSWORD_MODE", "ask")
+	store.SetArray("LOCKBOX_CREDENTIALS_PASSWORD", []string{"test"})
+	if _, err := config.NewKey(config.IgnoreKeyMode); err == nil || err.Error() != "key can NOT be set in this key mode" {
+		t.Errorf("invalid error: %v", err)
+	}
+	store.SetArray("LOCKBOX_CREDENTIALS_PASSWORD", []string{})
+	if _, err := config.NewKey(config.IgnoreKeyMode); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestShardsKey(t *testing.T) {
+	store.Clear()
+	defer store.Clear()
+	store.SetString("LOCKBOX_CREDENTIALS_PASSWORD_MODE", "shards")
+	store.SetArray("LOCKBOX_CREDENTIALS_PASSWORD", []string{"test"})
+	if _, err := config.NewKey(config.IgnoreKeyMode); err == nil || err.Error() != "key can NOT be set in this key mode" {
+		t.Errorf("invalid error: %v", err)
+	}
+	store.SetArray("LOCKBOX_CREDENTIALS_PASSWORD", []string{})
+	if _, err := config.NewKey(config.IgnoreKeyMode); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	shares, _ := shamir.Split([]byte("shardkey"), 3, 2)
+	var prompts []string
+	b, err := config.ReadShards(func(prompt string) (string, error) {
+		prompts = append(prompts, prompt)
+		return shares[len(prompts)].String(), nil
+	})
+	if err != nil || string(b) != "shardkey" {
+		t.Errorf("invalid shards: %s %v", string(b), err)
+	}
+	if strings.Join(prompts, "|") != "please enter shard 1: |please enter shard 2: " {
+		t.Errorf("invalid prompts: %v", prompts)
+	}
+	if _, err := config.ReadShards(func(string) (string, error) {
+		return "invalid", nil
+	}); err == nil || err.Error() != "invalid shard format" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestAskPassKey(t *testing.T) {
+	store.Clear()
+	defer store.Clear()
+	t.Setenv("SSH_ASKPASS", "")
+	store.SetString("LOCKBOX_CREDENTIALS_PASSWORD_MODE", "askpass")
+	k, err := config.NewKey(config.IgnoreKeyMode)
+	if err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := k.Read(); err == nil || err.Error() != "no askpass helper set and SSH_ASKPASS is not set" {
+		t.Errorf("invalid error: %v", err)
+	}
+	t.Setenv("SSH_ASKPASS", "/bin/echo")
+	val, err := k.Read()
+	if err != nil || val != "please enter password:" {
+		t.Errorf("invalid read: %s %v", val, err)
+	}
+	called := filepath.Join(t.TempDir(), "called")
+	store.SetArray("LOCKBOX_CREDENTIALS_PASSWORD", []string{"/bin/sh", "-c", "echo $0 >> " + called + "; echo secret"})
+	k, err = config.NewKey(config.IgnoreKeyMode)
+	if err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	for range 2 {
+		val, err = k.Read()
+		if err != nil || val != "secret" {
+			t.Errorf("invalid read: %s %v", val, err)
+		}
+	}
+	b, err := os.ReadFile(called)
+	if err != nil || string(b) != "please enter password:\n" {
+		t.Errorf("helper should be called once with prompt: %s %v", string(b), err)
+	}
+	config.ForgetPromptedKeys()
+	val, err = k.Read()
+	if err != nil || val != "secret" {
+		t.Errorf("invalid read: %s %v", val, err)
+	}
+	b, err = os.ReadFile(called)
+	if err != nil || string(b) != "please enter password:\nplease enter password:\n" {
+		t.Errorf("helper should be called again once forgotten: %s %v", string(b), err)
+	}
+}
+
+func TestPinentryKey(t *testing.T) {
+	store.Clear()
+	defer store.Clear()
+	script := filepath.Join(t.TempDir(), "pinentry")
+	os.WriteFile(script, []byte(`#!/bin/sh
+echo "OK"
+while read -r cmd rest; do
+  case "$cmd" in
+    GETPIN) echo "D pinpass"; echo "OK" ;;
+    *) echo "OK" ;;
+  esac
+done
+`), 0o700)
+	store.SetString("LOCKBOX_CREDENTIALS_PASSWORD_MODE", "pinentry")
+	store.SetArray("LOCKBOX_CREDENTIALS_PASSWORD", []string{script})
+	k, err := config.NewKey(config.IgnoreKeyMode)
+	if err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	val, err := k.Read()
+	if err != nil || val != "pinpass" {
+		t.Errorf("invalid read: %s %v", val, err)
+	}
+}
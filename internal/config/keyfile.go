@@ -0,0 +1,117 @@
+// Package config handles reading keyfile data
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type (
+	// KeyFileModeType are valid ways to get the keyfile
+	KeyFileModeType string
+	// KeyFile is a wrapper to help manage reading keyfile data
+	KeyFile struct {
+		mode   KeyFileModeType
+		source []string
+	}
+)
+
+const (
+	// FileKeyFileMode will read the keyfile from disk
+	FileKeyFileMode KeyFileModeType = "file"
+	// CommandKeyFileMode will read the keyfile from a command's output
+	CommandKeyFileMode KeyFileModeType = "command"
+	// FDKeyFileMode will read the keyfile from an inherited file descriptor
+	FDKeyFileMode KeyFileModeType = "fd"
+)
+
+var readKeyFiles = make(map[string][]byte)
+
+// NewKeyFile will create a keyfile from the configured settings
+func NewKeyFile() (KeyFile, error) {
+	mode := KeyFileModeType(EnvKeyFileMode.Get())
+	if mode == "" {
+		mode = FileKeyFileMode
+	}
+	var source []string
+	switch mode {
+	case CommandKeyFileMode:
+		source = EnvKeyFileCommand.Get()
+	default:
+		if file := EnvKeyFile.Get(); file != "" {
+			source = []string{file}
+		}
+	}
+	return NewKeyFileSource(mode, source...)
+}
+
+// NewKeyFileSource will create a keyfile for an explicit mode and source
+func NewKeyFileSource(mode KeyFileModeType, source ...string) (KeyFile, error) {
+	switch mode {
+	case FileKeyFileMode, CommandKeyFileMode, FDKeyFileMode:
+	default:
+		return KeyFile{}, fmt.Errorf("unknown keyfile mode: %s", mode)
+	}
+	var use []string
+	for _, s := range source {
+		if strings.TrimSpace(s) != "" {
+			use = append(use, s)
+		}
+	}
+	if mode != FileKeyFileMode && len(use) == 0 {
+		return KeyFile{}, fmt.Errorf("keyfile MUST be set in %s keyfile mode", mode)
+	}
+	return KeyFile{mode: mode, source: use}, nil
+}
+
+// Read will read the keyfile data (nil if no keyfile is set)
+func (k KeyFile) Read() ([]byte, error) {
+	if len(k.source) == 0 {
+		return nil, nil
+	}
+	if k.mode == FileKeyFileMode {
+		file := k.source[0]
+		if _, err := os.Stat(file); err != nil {
+			return nil, errors.New("no keyfile found on disk")
+		}
+		return os.ReadFile(file)
+	}
+	// NOTE: commands (e.g. gpg) may prompt and fds can only be read once, only read once per process
+	id := strings.Join(append([]string{string(k.mode)}, k.source...), " ")
+	if data, ok := readKeyFiles[id]; ok {
+		return data, nil
+	}
+	var data []byte
+	switch k.mode {
+	case CommandKeyFileMode:
+		b, err := runCommand(k.source)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read keyfile: %w", err)
+		}
+		data = b
+	case FDKeyFileMode:
+		fd, err := strconv.Atoi(strings.TrimSpace(k.source[0]))
+		if err != nil || fd < 0 {
+			return nil, fmt.Errorf("invalid keyfile fd: %s", k.source[0])
+		}
+		f := os.NewFile(uintptr(fd), "keyfile")
+		if f == nil {
+			return nil, fmt.Errorf("invalid keyfile fd: %d", fd)
+		}
+		b, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read keyfile: %w", err)
+		}
+		data = b
+	}
+	if len(data) == 0 {
+		return nil, errors.New("keyfile is empty")
+	}
+	readKeyFiles[id] = data
+	return data, nil
+}
@@ -0,0 +1,105 @@
+package config_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/enckse/lockbox/internal/config"
+	"github.com/enckse/lockbox/internal/config/store"
+)
+
+func TestKeyFileErrors(t *testing.T) {
+	store.Clear()
+	defer store.Clear()
+	store.SetString("LOCKBOX_CREDENTIALS_KEY_FILE_MODE", "invalid")
+	if _, err := config.NewKeyFile(); err == nil || err.Error() != "unknown keyfile mode: invalid" {
+		t.Errorf("invalid error: %v", err)
+	}
+	store.SetString("LOCKBOX_CREDENTIALS_KEY_FILE_MODE", "command")
+	if _, err := config.NewKeyFile(); err == nil || err.Error() != "keyfile MUST be set in command keyfile mode" {
+		t.Errorf("invalid error: %v", err)
+	}
+	store.SetString("LOCKBOX_CREDENTIALS_KEY_FILE_MODE", "fd")
+	if _, err := config.NewKeyFile(); err == nil || err.Error() != "keyfile MUST be set in fd keyfile mode" {
+		t.Errorf("invalid error: %v", err)
+	}
+	store.SetString("LOCKBOX_CREDENTIALS_KEY_FILE", "abc")
+	k, err := config.NewKeyFile()
+	if err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := k.Read(); err == nil || err.Error() != "invalid keyfile fd: abc" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestKeyFileFile(t *testing.T) {
+	store.Clear()
+	defer store.Clear()
+	k, err := config.NewKeyFile()
+	if err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if b, err := k.Read(); err != nil || b != nil {
+		t.Errorf("invalid read: %v %v", b, err)
+	}
+	file := filepath.Join(t.TempDir(), "file.key")
+	store.SetString("LOCKBOX_CREDENTIALS_KEY_FILE", file)
+	k, _ = config.NewKeyFile()
+	if _, err := k.Read(); err == nil || err.Error() != "no keyfile found on disk" {
+		t.Errorf("invalid error: %v", err)
+	}
+	os.WriteFile(file, []byte("test"), 0o600)
+	if b, err := k.Read(); err != nil || string(b) != "test" {
+		t.Errorf("invalid read: %s %v", string(b), err)
+	}
+}
+
+func TestKeyFileCommand(t *testing.T) {
+	store.Clear()
+	defer store.Clear()
+	store.SetString("LOCKBOX_CREDENTIALS_KEY_FILE_MODE", "command")
+	store.SetArray("LOCKBOX_CREDENTIALS_KEY_FILE_COMMAND", []string{"/bin/sh", "-c", "printf ''"})
+	k, _ := config.NewKeyFile()
+	if _, err := k.Read(); err == nil || err.Error() != "keyfile is empty" {
+		t.Errorf("invalid error: %v", err)
+	}
+	store.SetArray("LOCKBOX_CREDENTIALS_KEY_FILE_COMMAND", []string{"/bin/sh", "-c", "exit 1"})
+	k, _ = config.NewKeyFile()
+	if _, err := k.Read(); err == nil || err.Error() != "unable to read keyfile: key command failed: exit status 1" {
+		t.Errorf("invalid error: %v", err)
+	}
+	counter := filepath.Join(t.TempDir(), "counter")
+	store.SetArray("LOCKBOX_CREDENTIALS_KEY_FILE_COMMAND", []string{"/bin/sh", "-c", "echo x >> " + counter + "; printf 'keydata\\n'"})
+	k, _ = config.NewKeyFile()
+	for range 2 {
+		if b, err := k.Read(); err != nil || string(b) != "keydata\n" {
+			t.Errorf("invalid read: %s %v", string(b), err)
+		}
+	}
+	if b, _ := os.ReadFile(counter); string(b) != "x\n" {
+		t.Errorf("command should only run once: %s", string(b))
+	}
+}
+
+func TestKeyFileFD(t *testing.T) {
+	store.Clear()
+	defer store.Clear()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("invalid error: %v", err)
+	}
+	w.Write([]byte("fdkeydata"))
+	w.Close()
+	k, err := config.NewKeyFileSource(config.FDKeyFileMode, fmt.Sprintf("%d", r.Fd()))
+	if err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	for range 2 {
+		if b, err := k.Read(); err != nil || string(b) != "fdkeydata" {
+			t.Errorf("invalid read: %s %v", string(b), err)
+		}
+	}
+}
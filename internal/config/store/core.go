@@ -11,6 +11,7 @@ type (
 		strings  map[string]string
 		booleans map[string]bool
 		arrays   map[string][]string
+		tables   map[string]map[string]string
 	}
 
 	// KeyValue are values exportable for interrogation beyond the store
@@ -28,6 +29,7 @@ func newConfig() backing {
 	c.integers = make(map[string]int64)
 	c.booleans = make(map[string]bool)
 	c.strings = make(map[string]string)
+	c.tables = make(map[string]map[string]string)
 	return c
 }
 
@@ -43,6 +45,7 @@ func List(filter ...string) []KeyValue {
 	results = append(results, list(configuration.booleans, GetBool, filter)...)
 	results = append(results, list(configuration.strings, GetString, filter)...)
 	results = append(results, list(configuration.arrays, GetArray, filter)...)
+	results = append(results, list(configuration.tables, GetTable, filter)...)
 	return results
 }
 
@@ -81,6 +84,11 @@ func GetArray(key string) ([]string, bool) {
 	return get(key, configuration.arrays)
 }
 
+// GetTable will get a table value
+func GetTable(key string) (map[string]string, bool) {
+	return get(key, configuration.tables)
+}
+
 func get[T any](key string, m map[string]T) (T, bool) {
 	val, ok := m[key]
 	return val, ok
@@ -105,3 +113,8 @@ func SetString(key, val string) {
 func SetArray(key string, val []string) {
 	configuration.arrays[key] = val
 }
+
+// SetTable will set a table value
+func SetTable(key string, val map[string]string) {
+	configuration.tables[key] = val
+}
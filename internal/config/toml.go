@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/BurntSushi/toml"
@@ -21,6 +22,7 @@ const (
 	tomlBool    = "boolean"
 	tomlString  = "string"
 	tomlArray   = "[]string"
+	tomlTable   = "table"
 	fileKey     = "file"
 	requiredKey = "required"
 )
@@ -167,6 +169,12 @@ func Load(r io.Reader, loader Loader) error {
 				return err
 			}
 			store.SetArray(export, array)
+		case tomlTable:
+			table, err := parseTable(v)
+			if err != nil {
+				return err
+			}
+			store.SetTable(export, table)
 		case tomlInt:
 			i, ok := v.(int64)
 			if !ok {
@@ -322,6 +330,32 @@ func parseArray[T any](value any, expand, allowMap bool, creator func(string, bo
 	return res, nil
 }
 
+func parseTable(value any) (map[string]string, error) {
+	m, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("value is not of table type: %v", value)
+	}
+	res := make(map[string]string)
+	for k, v := range m {
+		switch t := v.(type) {
+		case string:
+			res[k] = t
+		case int64:
+			res[k] = strconv.FormatInt(t, 10)
+		case bool:
+			res[k] = strconv.FormatBool(t)
+		default:
+			return nil, newTypeError("string", v)
+		}
+	}
+	return res, nil
+}
+
+func isTable(key string) bool {
+	env, ok := registry[environmentPrefix+strings.ToUpper(key)]
+	return ok && env.display().tomlType == tomlTable
+}
+
 func flatten(m map[string]any, prefix string) map[string]any {
 	flattened := make(map[string]any)
 	for k, v := range m {
@@ -332,6 +366,10 @@ func flatten(m map[string]any, prefix string) map[string]any {
 
 		switch to := v.(type) {
 		case map[string]any:
+			if isTable(key) {
+				flattened[key] = v
+				continue
+			}
 			maps.Copy(flattened, flatten(to, key))
 		default:
 			flattened[key] = v
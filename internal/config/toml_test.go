@@ -180,6 +180,52 @@ copy = ["'xyz/$TEST'", "s"]
 	}
 }
 
+func TestTableLoad(t *testing.T) {
+	store.Clear()
+	data := `
+[rotation]
+policies = ["prod/*=90d"]
+`
+	r := strings.NewReader(data)
+	if err := config.Load(r, mockReader{}); err == nil || err.Error() != "value is not of table type: [prod/*=90d]" {
+		t.Errorf("invalid error: %v", err)
+	}
+	data = `
+[rotation.policies]
+"prod/*" = [90]
+`
+	r = strings.NewReader(data)
+	if err := config.Load(r, mockReader{}); err == nil || err.Error() != "non-string found where string expected: [90]" {
+		t.Errorf("invalid error: %v", err)
+	}
+	data = `include = []
+[rotation]
+policies = { "prod/*" = "90d", personal = "26w" }
+[policy]
+min_length = { "prod/*" = 16 }
+generated = { "prod/db/*" = true }
+`
+	r = strings.NewReader(data)
+	if err := config.Load(r, mockReader{}); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if len(store.List()) != 3 {
+		t.Errorf("invalid store")
+	}
+	val, ok := store.GetTable("LOCKBOX_ROTATION_POLICIES")
+	if fmt.Sprintf("%v", val) != "map[personal:26w prod/*:90d]" || !ok {
+		t.Errorf("invalid object: %v", val)
+	}
+	val, ok = store.GetTable("LOCKBOX_POLICY_MIN_LENGTH")
+	if fmt.Sprintf("%v", val) != "map[prod/*:16]" || !ok {
+		t.Errorf("invalid object: %v", val)
+	}
+	val, ok = store.GetTable("LOCKBOX_POLICY_GENERATED")
+	if fmt.Sprintf("%v", val) != "map[prod/db/*:true]" || !ok {
+		t.Errorf("invalid object: %v", val)
+	}
+}
+
 func TestReadInt(t *testing.T) {
 	store.Clear()
 	data := `
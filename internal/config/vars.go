@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/enckse/lockbox/internal/keyring"
 	"github.com/enckse/lockbox/internal/output"
+	"github.com/enckse/lockbox/internal/platform/pinentry"
 )
 
 var (
@@ -60,6 +62,38 @@ This value is appended to the single character type field`, output.JSONModes.Has
 			}),
 		short: "max totp time",
 	})
+	// EnvTOTPNextWithin is when to show the next TOTP code (before rollover)
+	EnvTOTPNextWithin = environmentRegister(EnvironmentInt{
+		environmentDefault: newDefaultedEnvironment(5,
+			environmentBase{
+				key:         totpCategory + "NEXT_WITHIN",
+				description: "Time, in seconds, before a TOTP code expires to also show the next code (0 disables).",
+			}),
+		short:   "next totp time",
+		canZero: true,
+	})
+	// EnvTOTPVerifyWindows is how many windows (before/after the current) a verified code may be from
+	EnvTOTPVerifyWindows = environmentRegister(EnvironmentInt{
+		environmentDefault: newDefaultedEnvironment(1,
+			environmentBase{
+				key:         totpCategory + "VERIFY_WINDOWS",
+				description: "Number of periods before (and after) the current period a verified TOTP code may be from (0 only allows the current code).",
+			}),
+		short:   "totp verify windows",
+		canZero: true,
+	})
+	// EnvTOTPClockOffset is applied to the clock when generating TOTP codes
+	EnvTOTPClockOffset = environmentRegister(EnvironmentString{
+		environmentStrings: environmentStrings{
+			environmentDefault: newDefaultedEnvironment("0s",
+				environmentBase{
+					key:         totpCategory + "CLOCK_OFFSET",
+					description: "Offset applied to the clock when generating TOTP codes (for a drifting clock), e.g. '-30s' or '1m'.",
+				}),
+			flags:   []stringsFlags{canDefaultFlag},
+			allowed: []string{"duration"},
+		},
+	})
 	// EnvTOTPCheckOnInsert will indicate if TOTP tokens should be check for validity during the insert process
 	EnvTOTPCheckOnInsert = environmentRegister(EnvironmentBool{
 		environmentDefault: newDefaultedEnvironment(true,
@@ -81,6 +115,19 @@ This value is appended to the single character type field`, output.JSONModes.Has
 			flags:   []stringsFlags{canExpandFlag},
 		},
 	})
+	// EnvAuditLog is the (optional) append-only log of operations
+	EnvAuditLog = environmentRegister(EnvironmentString{
+		environmentStrings: environmentStrings{
+			environmentDefault: newDefaultedEnvironment("",
+				environmentBase{
+					key: auditCategory + "LOG",
+					description: `An append-only log of operations (command, entry, time, and a keyed hash of the value),
+records are chained via an HMAC keyed from the database credentials to detect tampering.`,
+				}),
+			allowed: []string{fileExample},
+			flags:   []stringsFlags{canExpandFlag},
+		},
+	})
 	// EnvClipCopy allows overriding the clipboard copy command
 	EnvClipCopy = environmentRegister(EnvironmentArray{
 		environmentStrings: environmentStrings{
@@ -92,6 +139,66 @@ This value is appended to the single character type field`, output.JSONModes.Has
 			flags: []stringsFlags{isCommandFlag},
 		},
 	})
+	// EnvClipBackend selects how the clipboard is accessed
+	EnvClipBackend = environmentRegister(EnvironmentString{
+		environmentStrings: environmentStrings{
+			environmentDefault: newDefaultedEnvironment(DetectClipBackend,
+				environmentBase{
+					key: clipCategory + "BACKEND",
+					description: fmt.Sprintf(`The clipboard backend, '%s' will use the platform clipboard commands (or the copy override)
+and will fall back to '%s' when no display is available. '%s' writes the value to the terminal
+(e.g. over SSH) as an escape sequence (wrapped for tmux/screen), the value can not be read back so
+it is never cleared.`, DetectClipBackend, OSC52ClipBackend, OSC52ClipBackend),
+				}),
+			allowed: []string{DetectClipBackend, OSC52ClipBackend},
+			flags:   []stringsFlags{canDefaultFlag},
+		},
+	})
+	// EnvClipSelection selects which clipboard selection is used
+	EnvClipSelection = environmentRegister(EnvironmentString{
+		environmentStrings: environmentStrings{
+			environmentDefault: newDefaultedEnvironment(ClipboardSelection,
+				environmentBase{
+					key:         clipCategory + "SELECTION",
+					description: "The selection to copy into (wl-copy, xclip, and osc52 backends).",
+				}),
+			allowed: []string{ClipboardSelection, PrimarySelection},
+			flags:   []stringsFlags{canDefaultFlag},
+		},
+	})
+	// EnvClipSensitive marks copied values as sensitive for clipboard managers
+	EnvClipSensitive = environmentRegister(EnvironmentBool{
+		environmentDefault: newDefaultedEnvironment(true,
+			environmentBase{
+				key: clipCategory + "SENSITIVE",
+				description: `Mark copied values as sensitive so clipboard managers do not store them ('wl-copy --sensitive',
+which also offers the x-kde-passwordManagerHint type, when the installed wl-copy supports it). xclip
+offers a single type (target, text) so it can not mark values as sensitive, extra types are not
+supported.`,
+			}),
+	})
+	// EnvClipPaste allows overriding the clipboard paste command
+	EnvClipPaste = environmentRegister(EnvironmentArray{
+		environmentStrings: environmentStrings{
+			environmentDefault: newDefaultedEnvironment("",
+				environmentBase{
+					key:         clipCategory + "PASTE",
+					description: "Override the detected platform paste command (used to check the clipboard before clearing).",
+				}),
+			flags: []stringsFlags{isCommandFlag},
+		},
+	})
+	// EnvClipTimeout is how long a value is left in the clipboard
+	EnvClipTimeout = environmentRegister(EnvironmentInt{
+		environmentDefault: newDefaultedEnvironment(45,
+			environmentBase{
+				key: clipCategory + "TIMEOUT",
+				description: `Time, in seconds, to leave a value in the clipboard before clearing it (0 disables clearing).
+The clipboard is only cleared if it still holds the copied value.`,
+			}),
+		short:   "clipboard timeout",
+		canZero: true,
+	})
 	// EnvTOTPColorBetween handles terminal coloring for TOTP windows (seconds)
 	EnvTOTPColorBetween = environmentRegister(EnvironmentArray{
 		environmentStrings: environmentStrings{
@@ -99,12 +206,72 @@ This value is appended to the single character type field`, output.JSONModes.Has
 				environmentBase{
 					key: totpCategory + "COLOR_WINDOWS",
 					description: fmt.Sprintf(`Override when to set totp generated outputs to different colors,
-must be a list of one (or more) rules where a '%s' delimits the start and end second (0-60 for each).`, TimeWindowSpan),
+must be a list of one (or more) rules where a '%s' delimits the start and end second (from 0 up to
+the token's period). Seconds are the time remaining before the code expires, relative to the token's
+period (previously they were the seconds remaining in the minute, such windows are moved into the
+period with a warning).`, TimeWindowSpan),
 				}),
 			flags:   []stringsFlags{canDefaultFlag},
 			allowed: exampleColorWindows,
 		},
 	})
+	// EnvRotationPolicies are the per-group password rotation policies
+	EnvRotationPolicies = environmentRegister(EnvironmentTable{
+		environmentBase: environmentBase{
+			key: rotationCategory + "POLICIES",
+			description: `Password rotation policies, a table of globs to the maximum age
+(e.g. { 'prod/*' = '90d' }). The age is a number of days ('d') or weeks ('w'). A glob applies to
+matching entries and to all entries within matching groups, the most specific match (the
+deepest matched path, then the longest glob) is used.`,
+		},
+		allowed: "{glob = age...}",
+	})
+	// EnvRotationWarn enables warnings when showing/clipping overdue secrets
+	EnvRotationWarn = environmentRegister(EnvironmentBool{
+		environmentDefault: newDefaultedEnvironment(false,
+			environmentBase{
+				key:         rotationCategory + "WARN",
+				description: "Warn when showing/clipping a secret that is overdue for rotation.",
+			}),
+	})
+	// EnvPolicyMinLength are the per-group minimum password lengths
+	EnvPolicyMinLength = environmentRegister(EnvironmentTable{
+		environmentBase: environmentBase{
+			key: policyCategory + "MIN_LENGTH",
+			description: `Minimum password lengths, a table of globs to the length (e.g. { 'prod/*' = 16 }).
+A glob applies to matching entries and to all entries within matching groups, the most specific
+match (the deepest matched path, then the longest glob) is used.`,
+		},
+		allowed: "{glob = length...}",
+	})
+	// EnvPolicyClasses are the per-group required password character classes
+	EnvPolicyClasses = environmentRegister(EnvironmentTable{
+		environmentBase: environmentBase{
+			key: policyCategory + "CLASSES",
+			description: `Required password character classes, a table of globs to a comma separated list of
+classes (e.g. { 'prod/*' = 'lower,upper,digit,symbol' }), the most specific match is used.`,
+		},
+		allowed: "{glob = 'class,class...'...}",
+	})
+	// EnvPolicyBanned are the per-group banned password patterns
+	EnvPolicyBanned = environmentRegister(EnvironmentTable{
+		environmentBase: environmentBase{
+			key: policyCategory + "BANNED",
+			description: `Banned password patterns, a table of globs to a regular expression
+(e.g. { '*' = '(?i)password' }), every matching glob applies (a more specific glob can not
+lift a ban).`,
+		},
+		allowed: "{glob = regex...}",
+	})
+	// EnvPolicyGenerated are the globs where passwords must be generated
+	EnvPolicyGenerated = environmentRegister(EnvironmentTable{
+		environmentBase: environmentBase{
+			key: policyCategory + "GENERATED",
+			description: `Where passwords must be generated (not entered) on insert, a table of globs to
+true/false (e.g. { 'prod/db/*' = true }), the most specific match is used.`,
+		},
+		allowed: "{glob = true|false...}",
+	})
 	// EnvKeyFile is an keyfile for the database
 	EnvKeyFile = environmentRegister(EnvironmentString{
 		environmentStrings: environmentStrings{
@@ -112,12 +279,40 @@ must be a list of one (or more) rules where a '%s' delimits the start and end se
 				environmentBase{
 					key:         credsCategory + "KEY_FILE",
 					requirement: requiredKeyOrKeyFile,
-					description: "A keyfile to access/protect the database.",
+					description: fmt.Sprintf("A keyfile to access/protect the database ('%s' mode) or the inherited file descriptor to read it from ('%s' mode).", FileKeyFileMode, FDKeyFileMode),
 				}),
 			allowed: []string{"keyfile"},
 			flags:   []stringsFlags{canDefaultFlag, canExpandFlag},
 		},
 	})
+	// EnvKeyFileMode indicates how the keyfile is read
+	EnvKeyFileMode = environmentRegister(EnvironmentString{
+		environmentStrings: environmentStrings{
+			environmentDefault: newDefaultedEnvironment(string(FileKeyFileMode),
+				environmentBase{
+					key:         credsCategory + "KEY_FILE_MODE",
+					requirement: "must be set to a valid mode when using a key file",
+					description: fmt.Sprintf(`How to retrieve the database keyfile. Set to '%s' to read the keyfile from the output of
+the keyfile command (e.g. to decrypt it) or '%s' to read it from an inherited file descriptor.
+The keyfile is never written to disk in these modes.`, CommandKeyFileMode, FDKeyFileMode),
+				}),
+			allowed: []string{string(CommandKeyFileMode), string(FDKeyFileMode), string(FileKeyFileMode)},
+			flags:   []stringsFlags{canDefaultFlag},
+		},
+	})
+	// EnvKeyFileCommand is the command to read the keyfile from
+	EnvKeyFileCommand = environmentRegister(EnvironmentArray{
+		environmentStrings: environmentStrings{
+			environmentDefault: newDefaultedEnvironment(unset,
+				environmentBase{
+					key:         credsCategory + "KEY_FILE_COMMAND",
+					requirement: fmt.Sprintf("must be set in '%s' keyfile mode", CommandKeyFileMode),
+					description: fmt.Sprintf("The command to run ('%s' keyfile mode) to retrieve the keyfile contents.", CommandKeyFileMode),
+				}),
+			allowed: []string{commandArgsExample},
+			flags:   []stringsFlags{canExpandFlag},
+		},
+	})
 	// EnvDefaultModTime is modtime override ability for entries
 	EnvDefaultModTime = environmentRegister(EnvironmentString{
 		environmentStrings: environmentStrings{
@@ -157,9 +352,63 @@ must be a list of one (or more) rules where a '%s' delimits the start and end se
 					key:         credsCategory + "PASSWORD_MODE",
 					requirement: "must be set to a valid mode when using a key",
 					description: fmt.Sprintf(`How to retrieve the database store password. Set to '%s' when only using a key file.
-Set to '%s' to ignore the set key value`, noKeyMode, IgnoreKeyMode),
+Set to '%s' to ignore the set key value. Set to '%s' to ask a running agent for a cached key
+before running the configured command. Set to '%s' to use the kernel keyring (linux only) as the cache instead.
+Set to '%s' to prompt for the password on the terminal, '%s' to use an askpass helper program,
+'%s' to use a pinentry program, or '%s' to reconstruct the password from shamir shards entered on the terminal.`, noKeyMode, IgnoreKeyMode, AgentKeyMode, KeyringKeyMode, AskKeyMode, AskPassKeyMode, PinentryKeyMode, ShardsKeyMode),
+				}),
+			allowed: []string{string(AgentKeyMode), string(AskKeyMode), string(AskPassKeyMode), string(commandKeyMode), string(IgnoreKeyMode), string(KeyringKeyMode), string(noKeyMode), string(PinentryKeyMode), string(plainKeyMode), string(ShardsKeyMode)},
+			flags:   []stringsFlags{canDefaultFlag},
+		},
+	})
+	// EnvAgentSocket is the socket the agent listens on
+	EnvAgentSocket = environmentRegister(EnvironmentString{
+		environmentStrings: environmentStrings{
+			environmentDefault: newDefaultedEnvironment("",
+				environmentBase{
+					key:         agentCategory + "SOCKET",
+					description: "Override the detected unix socket location used by the key caching agent.",
+				}),
+			allowed: []string{fileExample},
+			flags:   []stringsFlags{canExpandFlag},
+		},
+	})
+	// EnvAgentIdleTimeout is how long a cached key can go unused
+	EnvAgentIdleTimeout = environmentRegister(EnvironmentInt{
+		environmentDefault: newDefaultedEnvironment(900,
+			environmentBase{
+				key:         agentCategory + "IDLE_TIMEOUT",
+				description: "Time, in seconds, a cached key can go unused before the agent purges it.",
+			}),
+		short: "agent idle timeout",
+	})
+	// EnvAgentTimeout is the maximum time a key is cached
+	EnvAgentTimeout = environmentRegister(EnvironmentInt{
+		environmentDefault: newDefaultedEnvironment(3600,
+			environmentBase{
+				key:         agentCategory + "TIMEOUT",
+				description: "Time, in seconds, a cached key is held by the agent (regardless of use) before it is purged.",
+			}),
+		short: "agent timeout",
+	})
+	// EnvKeyringTimeout is how long a key is cached in the kernel keyring
+	EnvKeyringTimeout = environmentRegister(EnvironmentInt{
+		environmentDefault: newDefaultedEnvironment(900,
+			environmentBase{
+				key:         keyringCategory + "TIMEOUT",
+				description: "Time, in seconds, a key cached in the kernel keyring is kept before the kernel expires it.",
+			}),
+		short: "keyring timeout",
+	})
+	// EnvKeyringType is which kernel keyring caches the key
+	EnvKeyringType = environmentRegister(EnvironmentString{
+		environmentStrings: environmentStrings{
+			environmentDefault: newDefaultedEnvironment(string(keyring.UserKeyring),
+				environmentBase{
+					key:         keyringCategory + "TYPE",
+					description: "The kernel keyring to cache the key within (e.g. to limit caching to a terminal session).",
 				}),
-			allowed: []string{string(commandKeyMode), string(IgnoreKeyMode), string(noKeyMode), string(plainKeyMode)},
+			allowed: keyring.Types(),
 			flags:   []stringsFlags{canDefaultFlag},
 		},
 	})
@@ -169,9 +418,17 @@ Set to '%s' to ignore the set key value`, noKeyMode, IgnoreKeyMode),
 				environmentBase{
 					requirement: requiredKeyOrKeyFile,
 					key:         credsCategory + "PASSWORD",
-					description: fmt.Sprintf("The database password itself ('%s' mode) or command to run ('%s' mode) to retrieve the database password.",
+					description: fmt.Sprintf(`The database password itself ('%s' mode) or command to run ('%s', '%s', or '%s' mode) to retrieve the database password.
+In '%s' mode this is the askpass helper to run (defaults to %s), it is given the prompt as the final argument.
+In '%s' mode this is the pinentry program to run (defaults to '%s').`,
 						plainKeyMode,
-						commandKeyMode),
+						commandKeyMode,
+						AgentKeyMode,
+						KeyringKeyMode,
+						AskPassKeyMode,
+						askPassEnv,
+						PinentryKeyMode,
+						pinentry.DefaultCommand),
 				}),
 			allowed: []string{commandArgsExample, "password"},
 			flags:   []stringsFlags{canExpandFlag},
@@ -73,6 +73,19 @@ func TestMaxTOTP(t *testing.T) {
 	checkInt(config.EnvTOTPTimeout, "LOCKBOX_TOTP_TIMEOUT", "max totp time", 120, false, t)
 }
 
+func TestTOTPVerifyWindows(t *testing.T) {
+	checkInt(config.EnvTOTPVerifyWindows, "LOCKBOX_TOTP_VERIFY_WINDOWS", "totp verify windows", 1, true, t)
+}
+
+func TestAgentTimeouts(t *testing.T) {
+	checkInt(config.EnvAgentIdleTimeout, "LOCKBOX_AGENT_IDLE_TIMEOUT", "agent idle timeout", 900, false, t)
+	checkInt(config.EnvAgentTimeout, "LOCKBOX_AGENT_TIMEOUT", "agent timeout", 3600, false, t)
+}
+
+func TestKeyringTimeout(t *testing.T) {
+	checkInt(config.EnvKeyringTimeout, "LOCKBOX_KEYRING_TIMEOUT", "keyring timeout", 900, false, t)
+}
+
 func checkInt(e config.EnvironmentInt, key, text string, def int64, allowZero bool, t *testing.T) {
 	store.Clear()
 	val, err := e.Get()
@@ -140,6 +153,7 @@ func TestDefaultStrings(t *testing.T) {
 	for k, v := range map[string]config.EnvironmentString{
 		"hash":    config.EnvJSONMode,
 		"command": config.EnvPasswordMode,
+		"user":    config.EnvKeyringType,
 	} {
 		val := v.Get()
 		if val != k {
@@ -159,6 +173,7 @@ func TestEmptyStrings(t *testing.T) {
 		config.EnvStore,
 		config.EnvKeyFile,
 		config.EnvDefaultModTime,
+		config.EnvAgentSocket,
 	} {
 		val := v.Get()
 		if val != "" {
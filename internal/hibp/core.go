@@ -0,0 +1,135 @@
+// Package hibp handles offline lookups in a (sorted) have i been pwned SHA-1 password file
+package hibp
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	hashLength = sha1.Size * 2
+	separator  = ":"
+)
+
+var errInvalid = errors.New("invalid breach database (expected sorted SHA-1 HASH:COUNT lines)")
+
+// DB is an opened breach database
+type DB struct {
+	file *os.File
+	size int64
+}
+
+// Open will open a breach database (sorted by hash, e.g. pwned-passwords-sha1-ordered-by-hash)
+func Open(path string) (*DB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	db := &DB{file: f, size: info.Size()}
+	_, _, line, err := db.lineFrom(0)
+	if err == nil {
+		_, _, err = parse(line)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Close will close the underlying database file
+func (db *DB) Close() error {
+	return db.file.Close()
+}
+
+// Lookup will get the breach count for a password (0 if not found)
+func (db *DB) Lookup(password string) (int64, error) {
+	sum := sha1.Sum([]byte(password))
+	return db.lookupHash(strings.ToUpper(hex.EncodeToString(sum[:])))
+}
+
+func (db *DB) lookupHash(target string) (int64, error) {
+	lo, hi := int64(0), db.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, next, line, err := db.lineFrom(mid)
+		if err != nil {
+			return 0, err
+		}
+		if start >= hi {
+			hi = mid
+			continue
+		}
+		hash, count, err := parse(line)
+		if err != nil {
+			return 0, err
+		}
+		switch cmp := strings.Compare(target, hash); {
+		case cmp == 0:
+			return count, nil
+		case cmp < 0:
+			hi = start
+		default:
+			lo = next
+		}
+	}
+	return 0, nil
+}
+
+// lineFrom reads the first line starting at (or after) an offset, returning the line start/end offsets
+func (db *DB) lineFrom(offset int64) (int64, int64, string, error) {
+	start := offset
+	if offset > 0 {
+		start = offset - 1
+	}
+	reader := bufio.NewReader(io.NewSectionReader(db.file, start, db.size-start))
+	if offset > 0 {
+		skipped, err := reader.ReadString('\n')
+		start += int64(len(skipped))
+		if err != nil {
+			if err == io.EOF {
+				return db.size, db.size, "", nil
+			}
+			return 0, 0, "", err
+		}
+	}
+	if start >= db.size {
+		return db.size, db.size, "", nil
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return 0, 0, "", err
+	}
+	return start, start + int64(len(line)), strings.TrimRight(line, "\r\n"), nil
+}
+
+func parse(line string) (string, int64, error) {
+	hash, count, hasCount := strings.Cut(strings.TrimSpace(line), separator)
+	if len(hash) != hashLength {
+		return "", 0, errInvalid
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", 0, errInvalid
+	}
+	// NOTE: hash-only lists have no counts, a match is still a breach
+	value := int64(1)
+	if hasCount {
+		v, err := strconv.ParseInt(strings.TrimSpace(count), 10, 64)
+		if err != nil {
+			return "", 0, errInvalid
+		}
+		value = v
+	}
+	return strings.ToUpper(hash), value, nil
+}
@@ -0,0 +1,75 @@
+package hibp_test
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/enckse/lockbox/internal/hibp"
+)
+
+func writeDB(t *testing.T, passwords []string, withCounts bool) string {
+	var lines []string
+	for idx, p := range passwords {
+		sum := sha1.Sum([]byte(p))
+		line := strings.ToUpper(hex.EncodeToString(sum[:]))
+		if withCounts {
+			line = fmt.Sprintf("%s:%d", line, idx+1)
+		}
+		lines = append(lines, line)
+	}
+	slices.Sort(lines)
+	file := filepath.Join(t.TempDir(), "pwned.txt")
+	os.WriteFile(file, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0o600)
+	return file
+}
+
+func TestOpen(t *testing.T) {
+	if _, err := hibp.Open(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected error")
+	}
+	file := filepath.Join(t.TempDir(), "bad.txt")
+	os.WriteFile(file, []byte("not a hash\n"), 0o600)
+	if _, err := hibp.Open(file); err == nil || err.Error() != "invalid breach database (expected sorted SHA-1 HASH:COUNT lines)" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	var passwords []string
+	for i := range 500 {
+		passwords = append(passwords, fmt.Sprintf("password%d", i))
+	}
+	db, err := hibp.Open(writeDB(t, passwords, true))
+	if err != nil {
+		t.Fatalf("invalid error: %v", err)
+	}
+	defer db.Close()
+	for idx, p := range passwords {
+		count, err := db.Lookup(p)
+		if err != nil || count != int64(idx+1) {
+			t.Errorf("invalid lookup: %s %d %v", p, count, err)
+		}
+	}
+	for _, p := range []string{"", "notbreached", "password500"} {
+		if count, err := db.Lookup(p); err != nil || count != 0 {
+			t.Errorf("invalid lookup: %s %d %v", p, count, err)
+		}
+	}
+}
+
+func TestLookupNoCounts(t *testing.T) {
+	db, err := hibp.Open(writeDB(t, []string{"abc"}, false))
+	if err != nil {
+		t.Fatalf("invalid error: %v", err)
+	}
+	defer db.Close()
+	if count, err := db.Lookup("abc"); err != nil || count != 1 {
+		t.Errorf("invalid lookup: %d %v", count, err)
+	}
+}
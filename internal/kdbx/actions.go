@@ -8,7 +8,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/enckse/lockbox/internal/auditlog"
 	"github.com/enckse/lockbox/internal/config"
+	"github.com/enckse/lockbox/internal/memory"
 	"github.com/tobischo/gokeepasslib/v3"
 )
 
@@ -24,9 +26,12 @@ type (
 	moveData struct {
 		src     moveEntity
 		dst     moveEntity
+		source  string
+		path    string
 		move    bool
 		modTime time.Time
 		values  map[string]string
+		policy  InsertOptions
 	}
 
 	moveEntity struct {
@@ -39,6 +44,10 @@ func (t *Transaction) act(cb action) error {
 	if !t.valid {
 		return errors.New("invalid transaction")
 	}
+	// NOTE: the store is about to be unlocked so keep secrets out of core dumps
+	if err := memory.DisableCoreDumps(); err != nil {
+		return err
+	}
 	key, err := config.NewKey(config.DefaultKeyMode)
 	if err != nil {
 		return err
@@ -47,7 +56,14 @@ func (t *Transaction) act(cb action) error {
 	if err != nil {
 		return err
 	}
-	file := config.EnvKeyFile.Get()
+	keyFile, err := config.NewKeyFile()
+	if err != nil {
+		return err
+	}
+	file, err := keyFile.Read()
+	if err != nil {
+		return err
+	}
 	if !t.exists {
 		if err := create(t.file, k, file); err != nil {
 			return err
@@ -70,6 +86,14 @@ func (t *Transaction) act(cb action) error {
 	if len(db.Content.Root.Groups) != 1 {
 		return errors.New("kdbx must have ONE root group")
 	}
+	// NOTE: the log key is derived once (with credentials that unlocked the database) per transaction
+	if t.logKey == nil && config.EnvAuditLog.Get() != "" {
+		logKey, err := auditlog.NewKey(k, file)
+		if err != nil {
+			return err
+		}
+		t.logKey = &logKey
+	}
 	err = cb(Context{db: db})
 	if err != nil {
 		return err
@@ -92,21 +116,98 @@ func (t *Transaction) act(cb action) error {
 }
 
 // ReKey will change the credentials on a database
-func (t *Transaction) ReKey(pass, keyFile string) error {
+func (t *Transaction) ReKey(pass string, keyFile []byte) error {
 	creds, err := getCredentials(pass, keyFile)
 	if err != nil {
 		return err
 	}
-	return t.change(func(c Context) error {
+	log := config.EnvAuditLog.Get()
+	var logKey auditlog.Key
+	if log != "" {
+		logKey, err = auditlog.NewKey(pass, keyFile)
+		if err != nil {
+			return err
+		}
+	}
+	// NOTE: the log is re-signed (aside) before the database is written and only replaces the
+	// log once the database has the new credentials
+	var staged *auditlog.Staged
+	if err := t.change(func(c Context) error {
+		if log != "" {
+			s, err := auditlog.ReKey(log, *t.logKey, logKey)
+			if err != nil {
+				return err
+			}
+			staged = s
+		}
 		c.db.Credentials = creds
 		return nil
-	})
+	}); err != nil {
+		if staged != nil {
+			staged.Discard()
+		}
+		return err
+	}
+	if staged == nil {
+		return nil
+	}
+	if err := staged.Commit(); err != nil {
+		return err
+	}
+	t.logKey = &logKey
+	return nil
+}
+
+// Unlock will only unlock the database (for commands that record without another operation)
+func (t *Transaction) Unlock() error {
+	return t.act(func(Context) error { return nil })
+}
+
+// Record will append an operation to the audit log (when enabled), the transaction must have
+// unlocked the database already
+func (t *Transaction) Record(command, path, value string) error {
+	log := config.EnvAuditLog.Get()
+	if log == "" {
+		return nil
+	}
+	key, err := t.auditKey()
+	if err != nil {
+		return err
+	}
+	return auditlog.Append(log, key, command, path, value)
+}
+
+// VerifyLog will verify the audit log chain, returning the number of records
+func (t *Transaction) VerifyLog() (int, error) {
+	log := config.EnvAuditLog.Get()
+	if log == "" {
+		return 0, errors.New("audit log is not enabled")
+	}
+	if t.logKey == nil {
+		if err := t.Unlock(); err != nil {
+			return 0, err
+		}
+	}
+	key, err := t.auditKey()
+	if err != nil {
+		return 0, err
+	}
+	return auditlog.Verify(log, key)
+}
+
+func (t *Transaction) auditKey() (auditlog.Key, error) {
+	if t.logKey == nil {
+		return auditlog.Key{}, errors.New("database must be unlocked to use the audit log")
+	}
+	return *t.logKey, nil
 }
 
 func (t *Transaction) change(cb action) error {
 	if t.readonly {
 		return errors.New("unable to alter database in readonly mode")
 	}
+	// NOTE: only this action writes, a transaction can be reused (e.g. to read) after a change
+	defer func() { t.write = false }()
 	return t.act(func(c Context) error {
 		if err := c.db.UnlockProtectedEntries(); err != nil {
 			return err
@@ -189,8 +290,18 @@ func findAndDo(isAdd bool, entityName string, offset []string, opEntity *gokeepa
 	return g, e, done
 }
 
-// Move will move (one or more) source objects to destination location
+// Move will move (one or more) source objects to destination location (checked against password
+// policies at the destination)
 func (t *Transaction) Move(moves ...MoveRequest) error {
+	return t.MoveWithOptions(InsertOptions{}, moves...)
+}
+
+// MoveWithOptions is a move with password policy options
+func (t *Transaction) MoveWithOptions(policy InsertOptions, moves ...MoveRequest) error {
+	return t.move(policy, moves)
+}
+
+func (t *Transaction) move(policy InsertOptions, moves []MoveRequest) error {
 	if len(moves) == 0 {
 		return nil
 	}
@@ -238,7 +349,7 @@ func (t *Transaction) Move(moves ...MoveRequest) error {
 		}
 		sourceData := moveEntity{offset: sOffset, title: sTitle}
 		destData := moveEntity{offset: dOffset, title: dTitle}
-		requests = append(requests, moveData{src: sourceData, dst: destData, move: move.Destination != move.Source.Path, modTime: modTime, values: values})
+		requests = append(requests, moveData{src: sourceData, dst: destData, source: move.Source.Path, path: move.Destination, move: move.Destination != move.Source.Path, modTime: modTime, values: values, policy: policy})
 	}
 	return t.doMoves(requests)
 }
@@ -246,6 +357,11 @@ func (t *Transaction) Move(moves ...MoveRequest) error {
 func (t *Transaction) doMoves(requests []moveData) error {
 	return t.change(func(c Context) error {
 		for _, req := range requests {
+			override, err := c.checkPolicy(req.path, req.values, req.policy)
+			if err != nil {
+				return err
+			}
+			passwordModTime := c.passwordModTime(req)
 			c.removeEntity(req.src.offset, req.src.title)
 			if req.move {
 				c.removeEntity(req.dst.offset, req.dst.title)
@@ -253,6 +369,12 @@ func (t *Transaction) doMoves(requests []moveData) error {
 			e := gokeepasslib.NewEntry()
 			e.Values = append(e.Values, value(titleKey, req.dst.title))
 			e.Values = append(e.Values, value(modTimeKey, req.modTime.Format(time.RFC3339)))
+			if override != "" {
+				e.Values = append(e.Values, value(policyOverrideKey, override))
+			}
+			if passwordModTime != "" {
+				e.Values = append(e.Values, value(passwordModTimeKey, passwordModTime))
+			}
 			for k, v := range req.values {
 				if k != NotesField && strings.Contains(v, "\n") {
 					return fmt.Errorf("%s can NOT be multi-line", strings.ToLower(k))
@@ -268,9 +390,72 @@ func (t *Transaction) doMoves(requests []moveData) error {
 	})
 }
 
-// Insert is a move to the same location
+// passwordModTime is when the password of the request last changed, other values (and moving)
+// do not change it (entries that predate tracking it use their modification time)
+func (c Context) passwordModTime(req moveData) string {
+	password, ok := req.values[PasswordField]
+	if !ok {
+		return ""
+	}
+	if existing := c.findEntry(req.source); existing != nil && getValue(*existing, PasswordField) == password {
+		if mod := getValue(*existing, passwordModTimeKey); mod != "" {
+			return mod
+		}
+		return getValue(*existing, modTimeKey)
+	}
+	return req.modTime.Format(time.RFC3339)
+}
+
+// Insert is a move to the same location (checked against password policies)
 func (t *Transaction) Insert(path string, val EntityValues) error {
-	return t.Move(MoveRequest{Source: &Entity{Path: path, Values: val}, Destination: path})
+	return t.InsertWithOptions(path, val, InsertOptions{})
+}
+
+// InsertWithOptions is an insert with password policy options
+func (t *Transaction) InsertWithOptions(path string, val EntityValues, opts InsertOptions) error {
+	return t.move(opts, []MoveRequest{{Source: &Entity{Path: path, Values: val}, Destination: path}})
+}
+
+// UpdateOTP will replace the otp value of an existing entry in place, the modification time (and
+// other values) are kept (e.g. storing a HOTP counter is not a rotation)
+func (t *Transaction) UpdateOTP(path, otp string) error {
+	offset, title, err := splitComponents(path)
+	if err != nil {
+		return err
+	}
+	return t.change(func(c Context) error {
+		entry := findEntry(c.db.Content.Root.Groups[0].Groups, offset, title)
+		if entry == nil {
+			return errors.New("entry does not exist")
+		}
+		for idx, v := range entry.Values {
+			if v.Key == OTPField {
+				entry.Values[idx] = protectedValue(OTPField, otp)
+				return nil
+			}
+		}
+		return fmt.Errorf("entry has no %s value", OTPField)
+	})
+}
+
+func findEntry(groups []gokeepasslib.Group, offset []string, title string) *gokeepasslib.Entry {
+	for g := range groups {
+		if groups[g].Name != offset[0] {
+			continue
+		}
+		if len(offset) > 1 {
+			if e := findEntry(groups[g].Groups, offset[1:], title); e != nil {
+				return e
+			}
+			continue
+		}
+		for e := range groups[g].Entries {
+			if getPathName(groups[g].Entries[e]) == title {
+				return &groups[g].Entries[e]
+			}
+		}
+	}
+	return nil
 }
 
 // Remove will remove a single entity
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/enckse/lockbox/internal/config/store"
@@ -59,6 +60,44 @@ func TestKeyFile(t *testing.T) {
 	}
 }
 
+func TestXMLKeyFileHashCase(t *testing.T) {
+	store.Clear()
+	defer store.Clear()
+	file := testFile("xml_keyfile_test.kdbx")
+	keyFile := testFile("xml.key")
+	os.Remove(file)
+	store.SetString("LOCKBOX_STORE", file)
+	store.SetString("LOCKBOX_CREDENTIALS_KEY_FILE", keyFile)
+	store.SetString("LOCKBOX_CREDENTIALS_PASSWORD_MODE", "none")
+	var data []byte
+	var start int
+	// NOTE: the (8 hex character) hash may be all digits, then case can not be changed
+	for !strings.ContainsAny(string(data[start:min(start+8, len(data))]), "ABCDEF") {
+		data, _ = kdbx.NewKeyFile()
+		start = strings.Index(string(data), "Hash=\"") + len("Hash=\"")
+	}
+	os.WriteFile(keyFile, data, 0o644)
+	tr, err := kdbx.NewTransaction()
+	if err != nil {
+		t.Errorf("failed: %v", err)
+	}
+	if err := tr.Insert(kdbx.NewPath("a", "b"), map[string]string{"password": "t"}); err != nil {
+		t.Errorf("no error: %v", err)
+	}
+	lower := string(data[:start]) + strings.ToLower(string(data[start:start+8])) + string(data[start+8:])
+	if lower == string(data) {
+		t.Fatal("hash not changed")
+	}
+	os.WriteFile(keyFile, []byte(lower), 0o644)
+	tr, err = kdbx.NewTransaction()
+	if err != nil {
+		t.Errorf("failed: %v", err)
+	}
+	if e, err := tr.Get(kdbx.NewPath("a", "b"), kdbx.SecretValue); err != nil || e == nil {
+		t.Errorf("invalid entity: %v %v", e, err)
+	}
+}
+
 func setup(t *testing.T) *kdbx.Transaction {
 	return fullSetup(t, false)
 }
@@ -334,10 +373,105 @@ func TestReKey(t *testing.T) {
 	if err != nil {
 		t.Errorf("failed: %v", err)
 	}
-	if err := tr.ReKey("", ""); err == nil || err.Error() != "key and/or keyfile must be set" {
+	if err := tr.ReKey("", nil); err == nil || err.Error() != "key and/or keyfile must be set" {
+		t.Errorf("no error: %v", err)
+	}
+	if err := tr.ReKey("abc", nil); err != nil {
+		t.Errorf("no error: %v", err)
+	}
+}
+
+func TestReKeyAuditLog(t *testing.T) {
+	store.Clear()
+	defer store.Clear()
+	f := "rekey_log.kdbx"
+	file := testFile(f)
+	defer os.Remove(filepath.Join(testDir, f))
+	os.Remove(file)
+	log := filepath.Join(t.TempDir(), "audit.log")
+	store.SetString("LOCKBOX_STORE", file)
+	store.SetString("LOCKBOX_AUDIT_LOG", log)
+	store.SetArray("LOCKBOX_CREDENTIALS_PASSWORD", []string{"test"})
+	store.SetString("LOCKBOX_CREDENTIALS_PASSWORD_MODE", "plaintext")
+	tr, _ := kdbx.NewTransaction()
+	if err := tr.Record("show", "a/b", "value"); err == nil || err.Error() != "database must be unlocked to use the audit log" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := tr.Unlock(); err != nil {
+		t.Errorf("no error: %v", err)
+	}
+	if err := tr.Record("show", "a/b", "value"); err != nil {
+		t.Errorf("no error: %v", err)
+	}
+	if err := tr.ReKey("abc", nil); err != nil {
+		t.Errorf("no error: %v", err)
+	}
+	if err := tr.Record("rekey", "", ""); err != nil {
+		t.Errorf("no error: %v", err)
+	}
+	store.SetArray("LOCKBOX_CREDENTIALS_PASSWORD", []string{"abc"})
+	tr, _ = kdbx.NewTransaction()
+	if count, err := tr.VerifyLog(); err != nil || count != 2 {
+		t.Errorf("invalid verify: %d %v", count, err)
+	}
+	store.SetString("LOCKBOX_AUDIT_LOG", "")
+	if _, err := tr.VerifyLog(); err == nil || err.Error() != "audit log is not enabled" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestKeyFileCommand(t *testing.T) {
+	store.Clear()
+	defer store.Clear()
+	file := testFile("keyfile_command.kdbx")
+	keyFile := testFile("command.key")
+	os.Remove(file)
+	os.WriteFile(keyFile, []byte(strings.Repeat("ab", 32)), 0o600)
+	store.SetString("LOCKBOX_STORE", file)
+	store.SetString("LOCKBOX_CREDENTIALS_PASSWORD_MODE", "none")
+	store.SetString("LOCKBOX_CREDENTIALS_KEY_FILE_MODE", "command")
+	store.SetArray("LOCKBOX_CREDENTIALS_KEY_FILE_COMMAND", []string{"/bin/cat", keyFile})
+	tr, err := kdbx.NewTransaction()
+	if err != nil {
+		t.Errorf("failed: %v", err)
+	}
+	if err := tr.Insert(kdbx.NewPath("a", "b"), map[string]string{"password": "t"}); err != nil {
 		t.Errorf("no error: %v", err)
 	}
-	if err := tr.ReKey("abc", ""); err != nil {
+	store.SetString("LOCKBOX_CREDENTIALS_KEY_FILE_MODE", "file")
+	store.SetString("LOCKBOX_CREDENTIALS_KEY_FILE", keyFile)
+	tr, _ = kdbx.NewTransaction()
+	if _, err := tr.Get("a/b", kdbx.SecretValue); err != nil {
 		t.Errorf("no error: %v", err)
 	}
 }
+
+func TestUpdateOTP(t *testing.T) {
+	defer store.Clear()
+	store.Clear()
+	modTime := "2022-12-30T12:34:56-05:00"
+	store.SetString("LOCKBOX_DEFAULTS_MODTIME", modTime)
+	if err := fullSetup(t, false).Insert("test/a/vpn", map[string]string{"password": "pass", "otp": "otpauth://hotp/lb:vpn?secret=abc&counter=1"}); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := fullSetup(t, true).Insert("test/a/plain", map[string]string{"password": "pass"}); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	store.SetString("LOCKBOX_DEFAULTS_MODTIME", "")
+	if err := fullSetup(t, true).UpdateOTP("test/a/vpn", "otpauth://hotp/lb:vpn?secret=abc&counter=2"); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	e, err := fullSetup(t, true).Get("test/a/vpn", kdbx.SecretValue)
+	if err != nil || e.ModTime != modTime || e.Values["otp"] != "otpauth://hotp/lb:vpn?secret=abc&counter=2" || e.Values["password"] != "pass" {
+		t.Errorf("invalid entry: %v %v", e, err)
+	}
+	if err := fullSetup(t, true).UpdateOTP("test/a/missing", "x"); err == nil || err.Error() != "entry does not exist" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := fullSetup(t, true).UpdateOTP("test/b/vpn", "x"); err == nil || err.Error() != "entry does not exist" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := fullSetup(t, true).UpdateOTP("test/a/plain", "x"); err == nil || err.Error() != "entry has no otp value" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/enckse/lockbox/internal/auditlog"
 	"github.com/enckse/lockbox/internal/config"
 	"github.com/enckse/lockbox/internal/platform"
 	"github.com/tobischo/gokeepasslib/v3"
@@ -21,6 +22,10 @@ const (
 	titleKey    = "Title"
 	pathSep     = "/"
 	modTimeKey  = "ModTime"
+	// policyOverrideKey records a password inserted in violation of policy
+	policyOverrideKey = "PolicyOverride"
+	// passwordModTimeKey is when the password (not any other value) last changed
+	passwordModTimeKey = "PasswordModTime"
 )
 
 type (
@@ -35,6 +40,7 @@ type (
 		exists   bool
 		write    bool
 		readonly bool
+		logKey   *auditlog.Key
 	}
 	// Context handles operating on the underlying database
 	Context struct {
@@ -42,8 +48,11 @@ type (
 	}
 	// Entity are database objects from results and transactional changes
 	Entity struct {
-		Values EntityValues
-		Path   string
+		Values          EntityValues
+		Path            string
+		ModTime         string
+		PasswordModTime string
+		PolicyOverride  string
 	}
 )
 
@@ -92,25 +101,23 @@ func splitComponents(path string) ([]string, string, error) {
 	return parts, title, nil
 }
 
-func getCredentials(key, keyFile string) (*gokeepasslib.DBCredentials, error) {
+func getCredentials(key string, keyFile []byte) (*gokeepasslib.DBCredentials, error) {
 	hasKey := len(key) > 0
-	hasKeyFile := len(keyFile) > 0
+	hasKeyFile := keyFile != nil
 	if !hasKey && !hasKeyFile {
 		return nil, errors.New("key and/or keyfile must be set")
 	}
 	if hasKeyFile {
-		if !platform.PathExists(keyFile) {
-			return nil, errors.New("no keyfile found on disk")
-		}
+		keyFile = keyData(keyFile)
 		if !hasKey {
-			return gokeepasslib.NewKeyCredentials(keyFile)
+			return gokeepasslib.NewKeyDataCredentials(keyFile)
 		}
-		return gokeepasslib.NewPasswordAndKeyCredentials(key, keyFile)
+		return gokeepasslib.NewPasswordAndKeyDataCredentials(key, keyFile)
 	}
 	return gokeepasslib.NewPasswordCredentials(key), nil
 }
 
-func create(file, key, keyFile string) error {
+func create(file, key string, keyFile []byte) error {
 	root := gokeepasslib.NewGroup()
 	root.Name = "root"
 	db := gokeepasslib.NewDatabase(gokeepasslib.WithDatabaseKDBXVersion4())
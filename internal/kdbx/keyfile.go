@@ -0,0 +1,167 @@
+// Package kdbx handles keyfile generation/verification
+package kdbx
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+const (
+	keyFileSize      = 32
+	keyFileHashSize  = 4
+	keyFileGroupSize = 4
+)
+
+type (
+	// KeyFileFormat is the detected format of a keyfile
+	KeyFileFormat string
+	keyFileXML    struct {
+		XMLName xml.Name `xml:"KeyFile"`
+		Meta    struct {
+			Version string `xml:"Version"`
+		} `xml:"Meta"`
+		Key struct {
+			Data struct {
+				Hash  string `xml:"Hash,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"Data"`
+		} `xml:"Key"`
+	}
+)
+
+const (
+	// XMLV2KeyFile is a KeePass XML (v2.0) keyfile with a hash
+	XMLV2KeyFile KeyFileFormat = "xml (v2.0)"
+	// XMLV1KeyFile is a KeePass XML (v1.0) keyfile
+	XMLV1KeyFile KeyFileFormat = "xml (v1.0)"
+	// BinaryKeyFile is a raw 32-byte keyfile
+	BinaryKeyFile KeyFileFormat = "binary (32 bytes)"
+	// HexKeyFile is a 64 character hex keyfile
+	HexKeyFile KeyFileFormat = "hex (64 characters)"
+	// HashedKeyFile is any other file (hashed to derive the key)
+	HashedKeyFile KeyFileFormat = "hashed (arbitrary data)"
+)
+
+// NewKeyFile will generate a random KeePass XML (v2.0) keyfile
+func NewKeyFile() ([]byte, error) {
+	key := make([]byte, keyFileSize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	data := strings.ToUpper(hex.EncodeToString(key))
+	var groups []string
+	for len(data) > 0 {
+		size := min(keyFileGroupSize*2, len(data))
+		groups = append(groups, data[:size])
+		data = data[size:]
+	}
+	var lines []string
+	for len(groups) > 0 {
+		size := min(keyFileGroupSize, len(groups))
+		lines = append(lines, "\t\t\t"+strings.Join(groups[:size], " "))
+		groups = groups[size:]
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString("<KeyFile>\n")
+	buf.WriteString("\t<Meta>\n\t\t<Version>2.0</Version>\n\t</Meta>\n")
+	buf.WriteString("\t<Key>\n")
+	fmt.Fprintf(&buf, "\t\t<Data Hash=\"%s\">\n", keyFileHash(key))
+	buf.WriteString(strings.Join(lines, "\n"))
+	buf.WriteString("\n\t\t</Data>\n\t</Key>\n</KeyFile>\n")
+	return buf.Bytes(), nil
+}
+
+func keyFileHash(key []byte) string {
+	sum := sha256.Sum256(key)
+	return strings.ToUpper(hex.EncodeToString(sum[:keyFileHashSize]))
+}
+
+// VerifyKeyFile will detect the keyfile format and validate its integrity
+func VerifyKeyFile(data []byte) (KeyFileFormat, error) {
+	if len(data) == 0 {
+		return "", errors.New("keyfile is empty")
+	}
+	format, err := detectKeyFile(data)
+	if err != nil {
+		return "", err
+	}
+	if _, err := gokeepasslib.ParseKeyData(keyData(data)); err != nil {
+		return "", err
+	}
+	return format, nil
+}
+
+// keyData will resolve an xml (v2.0) keyfile to its raw key, the hash is checked without case
+// (as keepass does) where gokeepasslib requires it to be upper case
+func keyData(data []byte) []byte {
+	var parsed keyFileXML
+	if err := xml.Unmarshal(bytes.TrimSpace(data), &parsed); err != nil {
+		return data
+	}
+	if format, err := verifyXMLKeyFile(parsed); err != nil || format != XMLV2KeyFile {
+		return data
+	}
+	key, _ := hex.DecodeString(strings.Join(strings.Fields(parsed.Key.Data.Value), ""))
+	return key
+}
+
+func detectKeyFile(data []byte) (KeyFileFormat, error) {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("<")) {
+		var parsed keyFileXML
+		if err := xml.Unmarshal(trimmed, &parsed); err == nil {
+			return verifyXMLKeyFile(parsed)
+		}
+	}
+	switch len(data) {
+	case keyFileSize:
+		return BinaryKeyFile, nil
+	case keyFileSize * 2:
+		if _, err := hex.DecodeString(string(data)); err == nil {
+			return HexKeyFile, nil
+		}
+	}
+	return HashedKeyFile, nil
+}
+
+func verifyXMLKeyFile(parsed keyFileXML) (KeyFileFormat, error) {
+	value := strings.Join(strings.Fields(parsed.Key.Data.Value), "")
+	switch parsed.Meta.Version {
+	case "1.0", "1.00":
+		key, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid xml keyfile data: %w", err)
+		}
+		if len(key) != keyFileSize {
+			return "", fmt.Errorf("invalid xml keyfile data length: %d", len(key))
+		}
+		return XMLV1KeyFile, nil
+	case "2.0":
+		key, err := hex.DecodeString(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid xml keyfile data: %w", err)
+		}
+		if len(key) != keyFileSize {
+			return "", fmt.Errorf("invalid xml keyfile data length: %d", len(key))
+		}
+		hash := parsed.Key.Data.Hash
+		if hash == "" {
+			return "", errors.New("xml keyfile is missing the data hash")
+		}
+		if !strings.EqualFold(hash, keyFileHash(key)) {
+			return "", errors.New("xml keyfile hash mismatch")
+		}
+		return XMLV2KeyFile, nil
+	}
+	return "", fmt.Errorf("unknown xml keyfile version: %s", parsed.Meta.Version)
+}
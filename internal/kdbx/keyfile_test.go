@@ -0,0 +1,81 @@
+package kdbx_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/enckse/lockbox/internal/kdbx"
+)
+
+func TestNewKeyFile(t *testing.T) {
+	a, err := kdbx.NewKeyFile()
+	if err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	b, _ := kdbx.NewKeyFile()
+	if bytes.Equal(a, b) {
+		t.Error("keyfiles should be random")
+	}
+	if !strings.Contains(string(a), "<Version>2.0</Version>") || !strings.Contains(string(a), "<Data Hash=\"") {
+		t.Errorf("invalid keyfile: %s", string(a))
+	}
+	format, err := kdbx.VerifyKeyFile(a)
+	if err != nil || format != kdbx.XMLV2KeyFile {
+		t.Errorf("invalid verify: %s %v", format, err)
+	}
+}
+
+func TestVerifyKeyFile(t *testing.T) {
+	if _, err := kdbx.VerifyKeyFile(nil); err == nil || err.Error() != "keyfile is empty" {
+		t.Errorf("invalid error: %v", err)
+	}
+	const v2 = `<?xml version="1.0" encoding="UTF-8"?>
+<KeyFile>
+	<Meta>
+		<Version>2.0</Version>
+	</Meta>
+	<Key>
+		<Data Hash="%s">
+			A7007945 D07D54BA 28DF6434 1B4500FC
+			9750DFB1 D36ADA2D 9C32DC19 4C7AB01B
+		</Data>
+	</Key>
+</KeyFile>`
+	if format, err := kdbx.VerifyKeyFile([]byte(strings.Replace(v2, "%s", "FE2949B8", 1))); err != nil || format != kdbx.XMLV2KeyFile {
+		t.Errorf("invalid verify: %s %v", format, err)
+	}
+	if format, err := kdbx.VerifyKeyFile([]byte(strings.Replace(v2, "%s", "fe2949b8", 1))); err != nil || format != kdbx.XMLV2KeyFile {
+		t.Errorf("invalid verify: %s %v", format, err)
+	}
+	if _, err := kdbx.VerifyKeyFile([]byte(strings.Replace(v2, "%s", "FE2949B9", 1))); err == nil || err.Error() != "xml keyfile hash mismatch" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := kdbx.VerifyKeyFile([]byte(strings.Replace(v2, " Hash=\"%s\"", "", 1))); err == nil || err.Error() != "xml keyfile is missing the data hash" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := kdbx.VerifyKeyFile([]byte(strings.Replace(v2, "2.0", "3.0", 1))); err == nil || err.Error() != "unknown xml keyfile version: 3.0" {
+		t.Errorf("invalid error: %v", err)
+	}
+	const v1 = `<?xml version="1.0" encoding="utf-8"?>
+<KeyFile>
+	<Meta>
+		<Version>1.00</Version>
+	</Meta>
+	<Key>
+		<Data>pwB5RdB9VLoo32Q0G0UA/JdQ37HTatotnDLcGUx6sBs=</Data>
+	</Key>
+</KeyFile>`
+	if format, err := kdbx.VerifyKeyFile([]byte(v1)); err != nil || format != kdbx.XMLV1KeyFile {
+		t.Errorf("invalid verify: %s %v", format, err)
+	}
+	if format, err := kdbx.VerifyKeyFile(bytes.Repeat([]byte{1}, 32)); err != nil || format != kdbx.BinaryKeyFile {
+		t.Errorf("invalid verify: %s %v", format, err)
+	}
+	if format, err := kdbx.VerifyKeyFile([]byte(strings.Repeat("ab", 32))); err != nil || format != kdbx.HexKeyFile {
+		t.Errorf("invalid verify: %s %v", format, err)
+	}
+	if format, err := kdbx.VerifyKeyFile([]byte(strings.Repeat("zz", 32))); err != nil || format != kdbx.HashedKeyFile {
+		t.Errorf("invalid verify: %s %v", format, err)
+	}
+}
@@ -0,0 +1,228 @@
+// Package kdbx handles password policy enforcement
+package kdbx
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/enckse/lockbox/internal/config"
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+const (
+	generatedLength   = 32
+	generatedAttempts = 100
+)
+
+type (
+	// InsertOptions change how an insert is checked against password policies
+	InsertOptions struct {
+		// Generated indicates the password was generated (not entered)
+		Generated bool
+		// Override will insert (and record) a password that violates policy
+		Override bool
+	}
+	passwordPolicy struct {
+		minLength int
+		classes   []passwordClass
+		banned    []*regexp.Regexp
+		generated bool
+	}
+	passwordClass struct {
+		name  string
+		chars string
+		match func(rune) bool
+	}
+)
+
+var passwordClasses = []passwordClass{
+	{"lower", "abcdefghijklmnopqrstuvwxyz", unicode.IsLower},
+	{"upper", "ABCDEFGHIJKLMNOPQRSTUVWXYZ", unicode.IsUpper},
+	{"digit", "0123456789", unicode.IsDigit},
+	{"symbol", "!#$%&()*+,-./:;<=>?@[]^_{|}~", func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r)
+	}},
+}
+
+func policyGlobs(table map[string]string) ([]string, error) {
+	globs := slices.Sorted(maps.Keys(table))
+	for _, glob := range globs {
+		if strings.TrimSpace(glob) == "" {
+			return nil, fmt.Errorf("invalid password policy: %s", table[glob])
+		}
+		if _, err := Glob(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid password policy glob: %s", glob)
+		}
+	}
+	return globs, nil
+}
+
+func policySetting(table map[string]string, path string) (string, bool, error) {
+	globs, err := policyGlobs(table)
+	if err != nil {
+		return "", false, err
+	}
+	glob, ok := MatchGlob(globs, path)
+	if !ok {
+		return "", false, nil
+	}
+	return strings.TrimSpace(table[glob]), true, nil
+}
+
+func loadPasswordPolicy(path string) (passwordPolicy, error) {
+	var policy passwordPolicy
+	setting, ok, err := policySetting(config.EnvPolicyMinLength.Get(), path)
+	if err != nil {
+		return policy, err
+	}
+	if ok {
+		length, err := strconv.Atoi(setting)
+		if err != nil || length <= 0 {
+			return policy, fmt.Errorf("invalid password policy length: %s", setting)
+		}
+		policy.minLength = length
+	}
+	setting, ok, err = policySetting(config.EnvPolicyClasses.Get(), path)
+	if err != nil {
+		return policy, err
+	}
+	if ok {
+		for name := range strings.SplitSeq(setting, ",") {
+			name = strings.TrimSpace(name)
+			idx := slices.IndexFunc(passwordClasses, func(c passwordClass) bool {
+				return c.name == name
+			})
+			if idx < 0 {
+				return policy, fmt.Errorf("invalid password policy class: %s", name)
+			}
+			policy.classes = append(policy.classes, passwordClasses[idx])
+		}
+	}
+	// NOTE: every matching ban applies, a more specific glob can not lift a ban
+	banned := config.EnvPolicyBanned.Get()
+	globs, err := policyGlobs(banned)
+	if err != nil {
+		return policy, err
+	}
+	for _, glob := range globs {
+		if _, ok := MatchGlob([]string{glob}, path); !ok {
+			continue
+		}
+		r, err := regexp.Compile(strings.TrimSpace(banned[glob]))
+		if err != nil {
+			return policy, fmt.Errorf("invalid password policy pattern: %s", banned[glob])
+		}
+		policy.banned = append(policy.banned, r)
+	}
+	setting, ok, err = policySetting(config.EnvPolicyGenerated.Get(), path)
+	if err != nil {
+		return policy, err
+	}
+	if ok {
+		generated, err := strconv.ParseBool(setting)
+		if err != nil {
+			return policy, fmt.Errorf("invalid password policy generated: %s", setting)
+		}
+		policy.generated = generated
+	}
+	return policy, nil
+}
+
+func (p passwordPolicy) violations(password string, generated bool) []string {
+	var results []string
+	if p.generated && !generated {
+		results = append(results, "must be generated")
+	}
+	if length := len([]rune(password)); length < p.minLength {
+		results = append(results, fmt.Sprintf("minimum length %d (have %d)", p.minLength, length))
+	}
+	for _, class := range p.classes {
+		if !strings.ContainsFunc(password, class.match) {
+			results = append(results, fmt.Sprintf("missing %s characters", class.name))
+		}
+	}
+	for _, r := range p.banned {
+		if r.MatchString(password) {
+			results = append(results, fmt.Sprintf("banned pattern %s", r.String()))
+		}
+	}
+	return results
+}
+
+// GeneratePassword will generate a (random) password that meets the policy for a path
+func GeneratePassword(path string) (string, error) {
+	policy, err := loadPasswordPolicy(path)
+	if err != nil {
+		return "", err
+	}
+	var alphabet strings.Builder
+	for _, class := range passwordClasses {
+		alphabet.WriteString(class.chars)
+	}
+	chars := []rune(alphabet.String())
+	length := max(policy.minLength, generatedLength)
+	limit := big.NewInt(int64(len(chars)))
+	for range generatedAttempts {
+		password := make([]rune, length)
+		for idx := range password {
+			n, err := rand.Int(rand.Reader, limit)
+			if err != nil {
+				return "", err
+			}
+			password[idx] = chars[n.Int64()]
+		}
+		if len(policy.violations(string(password), true)) == 0 {
+			return string(password), nil
+		}
+	}
+	return "", fmt.Errorf("unable to generate a password for %s within policy", path)
+}
+
+func (c Context) findEntry(path string) *gokeepasslib.Entry {
+	var found *gokeepasslib.Entry
+	errFound := errors.New("found")
+	forEach("", c.db.Content.Root.Groups[0].Groups, c.db.Content.Root.Groups[0].Entries, func(offset string, entry gokeepasslib.Entry) error {
+		name := getPathName(entry)
+		if offset != "" {
+			name = NewPath(offset, name)
+		}
+		if name == path {
+			found = &entry
+			return errFound
+		}
+		return nil
+	})
+	return found
+}
+
+// checkPolicy will validate a (changed) password against policy, returning the override record to store
+func (c Context) checkPolicy(path string, values map[string]string, opts InsertOptions) (string, error) {
+	password, ok := values[PasswordField]
+	if !ok {
+		return "", nil
+	}
+	if existing := c.findEntry(path); existing != nil && getValue(*existing, PasswordField) == password {
+		return getValue(*existing, policyOverrideKey), nil
+	}
+	policy, err := loadPasswordPolicy(path)
+	if err != nil {
+		return "", err
+	}
+	violations := strings.Join(policy.violations(password, opts.Generated), "; ")
+	if violations == "" {
+		return "", nil
+	}
+	if !opts.Override {
+		return "", fmt.Errorf("password policy violation for %s: %s", path, violations)
+	}
+	return fmt.Sprintf("%s %s", time.Now().Format(config.ModTimeFormat), violations), nil
+}
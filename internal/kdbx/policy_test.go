@@ -0,0 +1,142 @@
+package kdbx_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/enckse/lockbox/internal/config/store"
+	"github.com/enckse/lockbox/internal/kdbx"
+)
+
+func setPolicies() {
+	store.SetTable("LOCKBOX_POLICY_MIN_LENGTH", map[string]string{"*": "8", "prod": "12", "prod/db": "20"})
+	store.SetTable("LOCKBOX_POLICY_CLASSES", map[string]string{"prod": "lower,digit"})
+	store.SetTable("LOCKBOX_POLICY_BANNED", map[string]string{"prod": "(?i)password", "*": "1234"})
+	store.SetTable("LOCKBOX_POLICY_GENERATED", map[string]string{"prod/gen": "true", "prod/gen/manual": "false"})
+}
+
+func TestPolicyInsert(t *testing.T) {
+	store.Clear()
+	defer store.Clear()
+	setup(t)
+	setPolicies()
+	if err := fullSetup(t, true).Insert(kdbx.NewPath("prod", "api"), map[string]string{"password": "Password"}); err == nil || err.Error() != "password policy violation for prod/api: minimum length 12 (have 8); missing digit characters; banned pattern (?i)password" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := fullSetup(t, true).Insert(kdbx.NewPath("prod", "db"), map[string]string{"password": "abcdefgh12345"}); err == nil || err.Error() != "password policy violation for prod/db: minimum length 20 (have 13); banned pattern 1234" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := fullSetup(t, true).Insert(kdbx.NewPath("prod", "gen", "a"), map[string]string{"password": "abcdefgh9876"}); err == nil || err.Error() != "password policy violation for prod/gen/a: must be generated" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := fullSetup(t, true).InsertWithOptions(kdbx.NewPath("prod", "gen", "a"), map[string]string{"password": "abcdefgh9876"}, kdbx.InsertOptions{Generated: true}); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := fullSetup(t, true).Insert(kdbx.NewPath("prod", "api"), map[string]string{"password": "abcdefgh9876"}); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := fullSetup(t, true).Insert(kdbx.NewPath("prod", "gen", "manual", "a"), map[string]string{"password": "abcdefgh9876"}); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := fullSetup(t, true).Insert(kdbx.NewPath("other", "api"), map[string]string{"password": "a"}); err == nil || err.Error() != "password policy violation for other/api: minimum length 8 (have 1)" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := fullSetup(t, true).Insert(kdbx.NewPath("other", "api"), map[string]string{"password": "abcdefgh"}); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	e, _ := fullSetup(t, true).Get(kdbx.NewPath("prod", "api"), kdbx.SecretValue)
+	if e == nil || e.PolicyOverride != "" {
+		t.Errorf("invalid entity: %v", e)
+	}
+	store.SetTable("LOCKBOX_POLICY_CLASSES", map[string]string{"prod": "lower,bad"})
+	if err := fullSetup(t, true).Insert(kdbx.NewPath("prod", "api"), map[string]string{"password": "abcdefgh98765"}); err == nil || err.Error() != "invalid password policy class: bad" {
+		t.Errorf("invalid error: %v", err)
+	}
+	store.SetTable("LOCKBOX_POLICY_CLASSES", map[string]string{"a/[": "lower"})
+	if err := fullSetup(t, true).Insert(kdbx.NewPath("prod", "api"), map[string]string{"password": "abcdefgh98765"}); err == nil || err.Error() != "invalid password policy glob: a/[" {
+		t.Errorf("invalid error: %v", err)
+	}
+	store.SetTable("LOCKBOX_POLICY_CLASSES", map[string]string{})
+	store.SetTable("LOCKBOX_POLICY_BANNED", map[string]string{"prod": "("})
+	if err := fullSetup(t, true).Insert(kdbx.NewPath("prod", "api"), map[string]string{"password": "abcdefgh98765"}); err == nil || err.Error() != "invalid password policy pattern: (" {
+		t.Errorf("invalid error: %v", err)
+	}
+	store.SetTable("LOCKBOX_POLICY_BANNED", map[string]string{})
+	store.SetTable("LOCKBOX_POLICY_GENERATED", map[string]string{"prod": "maybe"})
+	if err := fullSetup(t, true).Insert(kdbx.NewPath("prod", "api"), map[string]string{"password": "abcdefgh98765"}); err == nil || err.Error() != "invalid password policy generated: maybe" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestPolicyOverride(t *testing.T) {
+	store.Clear()
+	defer store.Clear()
+	setup(t)
+	setPolicies()
+	path := kdbx.NewPath("prod", "api")
+	if err := fullSetup(t, true).InsertWithOptions(path, map[string]string{"password": "short"}, kdbx.InsertOptions{Override: true}); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	e, _ := fullSetup(t, true).Get(path, kdbx.SecretValue)
+	if e == nil || !strings.HasSuffix(e.PolicyOverride, " minimum length 12 (have 5); missing digit characters") {
+		t.Errorf("invalid entity: %v", e)
+	}
+	if _, ok := e.Values["policyoverride"]; ok {
+		t.Error("override should not be a value")
+	}
+	override := e.PolicyOverride
+	e.Values["notes"] = "text"
+	if err := fullSetup(t, true).Insert(path, e.Values); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	e, _ = fullSetup(t, true).Get(path, kdbx.JSONValue)
+	if e == nil || e.PolicyOverride != override || e.Values["policyoverride"] != override {
+		t.Errorf("invalid entity: %v", e)
+	}
+	e, _ = fullSetup(t, true).Get(path, kdbx.SecretValue)
+	moved := kdbx.MoveRequest{Source: e, Destination: kdbx.NewPath("prod", "moved")}
+	if err := fullSetup(t, true).Move(moved); err == nil || err.Error() != "password policy violation for prod/moved: minimum length 12 (have 5); missing digit characters" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if e, _ := fullSetup(t, true).Get(kdbx.NewPath("prod", "moved"), kdbx.SecretValue); e != nil {
+		t.Errorf("invalid entity: %v", e)
+	}
+	if err := fullSetup(t, true).MoveWithOptions(kdbx.InsertOptions{Override: true}, moved); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	e, _ = fullSetup(t, true).Get(kdbx.NewPath("prod", "moved"), kdbx.SecretValue)
+	if e == nil || !strings.HasSuffix(e.PolicyOverride, " minimum length 12 (have 5); missing digit characters") {
+		t.Errorf("invalid entity: %v", e)
+	}
+	if e, _ := fullSetup(t, true).Get(path, kdbx.SecretValue); e != nil {
+		t.Errorf("invalid entity: %v", e)
+	}
+	e.Values["password"] = "abcdefgh9876"
+	if err := fullSetup(t, true).Insert(e.Path, e.Values); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	e, _ = fullSetup(t, true).Get(e.Path, kdbx.SecretValue)
+	if e == nil || e.PolicyOverride != "" {
+		t.Errorf("invalid entity: %v", e)
+	}
+}
+
+func TestGeneratePassword(t *testing.T) {
+	store.Clear()
+	defer store.Clear()
+	setPolicies()
+	store.SetTable("LOCKBOX_POLICY_MIN_LENGTH", map[string]string{"prod/db": "40"})
+	store.SetTable("LOCKBOX_POLICY_CLASSES", map[string]string{"prod": "lower,upper,digit,symbol"})
+	p, err := kdbx.GeneratePassword(kdbx.NewPath("prod", "db", "a"))
+	if err != nil || len(p) != 40 || strings.Contains(p, "1234") {
+		t.Errorf("invalid password: %s %v", p, err)
+	}
+	p, err = kdbx.GeneratePassword(kdbx.NewPath("other", "a"))
+	if err != nil || len(p) != 32 {
+		t.Errorf("invalid password: %s %v", p, err)
+	}
+	store.SetTable("LOCKBOX_POLICY_BANNED", map[string]string{"prod": "."})
+	if _, err := kdbx.GeneratePassword(kdbx.NewPath("prod", "a")); err == nil || err.Error() != "unable to generate a password for prod/a within policy" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
@@ -2,6 +2,7 @@
 package kdbx
 
 import (
+	"cmp"
 	"errors"
 	"path/filepath"
 	"slices"
@@ -102,6 +103,23 @@ func Glob(criteria, path string) (bool, error) {
 	return filepath.Match(criteria, path)
 }
 
+// MatchGlob will find the most specific glob matching a path (the entry or a group containing it),
+// the deepest matched path is used first and then the longest glob
+func MatchGlob(globs []string, path string) (string, bool) {
+	sorted := slices.Sorted(slices.Values(globs))
+	slices.SortStableFunc(sorted, func(a, b string) int {
+		return cmp.Compare(len(b), len(a))
+	})
+	for check := path; check != ""; check = Directory(check) {
+		for _, glob := range sorted {
+			if ok, _ := Glob(glob, check); ok {
+				return glob, true
+			}
+		}
+	}
+	return "", false
+}
+
 // QueryCallback will retrieve a query based on setting
 func (t *Transaction) QueryCallback(args QueryOptions) (QuerySeq2, error) {
 	if args.Mode == noneMode {
@@ -165,7 +183,7 @@ func (t *Transaction) QueryCallback(args QueryOptions) (QuerySeq2, error) {
 	return func(yield func(Entity, error) bool) {
 		for _, item := range entities {
 			hasher.Reset()
-			entity := Entity{Path: item.path}
+			entity := Entity{Path: item.path, ModTime: getValue(item.backing, modTimeKey), PasswordModTime: getValue(item.backing, passwordModTimeKey), PolicyOverride: getValue(item.backing, policyOverrideKey)}
 			var err error
 			values := make(EntityValues)
 			for _, v := range item.backing.Values {
@@ -174,11 +192,14 @@ func (t *Transaction) QueryCallback(args QueryOptions) (QuerySeq2, error) {
 				if args.Values != BlankValue {
 					if args.Values == JSONValue {
 						values["modtime"] = getValue(item.backing, modTimeKey)
+						if entity.PolicyOverride != "" {
+							values["policyoverride"] = entity.PolicyOverride
+						}
 					}
 					raw = v.Value.Content
 					val = hasher.Transform(raw)
 				}
-				if key == modTimeKey || key == titleKey {
+				if key == modTimeKey || key == titleKey || key == policyOverrideKey || key == passwordModTimeKey {
 					continue
 				}
 				field := strings.ToLower(key)
@@ -0,0 +1,92 @@
+// Package keyring handles caching unlocked keys in the kernel keyring
+package keyring
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// UserKeyring is the per-user keyring
+	UserKeyring Type = "user"
+	// SessionKeyring is the per-session keyring
+	SessionKeyring    Type = "session"
+	descriptionPrefix      = "lockbox:"
+)
+
+var (
+	// ErrNotFound indicates no key is cached
+	ErrNotFound = errors.New("no cached key")
+	// ErrUnsupported indicates the platform has no kernel keyring
+	ErrUnsupported = errors.New("kernel keyring is unsupported on this platform")
+)
+
+// Type is the keyring to operate on
+type Type string
+
+// Types are the allowed keyring types
+func Types() []string {
+	return []string{string(SessionKeyring), string(UserKeyring)}
+}
+
+func description(id string) (string, error) {
+	if id == "" {
+		return "", errors.New("no key identifier given")
+	}
+	return descriptionPrefix + id, nil
+}
+
+func parseType(t Type) (Type, error) {
+	switch t {
+	case UserKeyring, SessionKeyring:
+		return t, nil
+	}
+	return "", fmt.Errorf("unknown keyring: %s", t)
+}
+
+// Get will read a cached key from the keyring
+func Get(t Type, id string) ([]byte, error) {
+	ring, err := parseType(t)
+	if err != nil {
+		return nil, err
+	}
+	desc, err := description(id)
+	if err != nil {
+		return nil, err
+	}
+	return get(ring, desc)
+}
+
+// Set will cache a key in the keyring, it expires after the timeout
+func Set(t Type, id string, data []byte, timeout time.Duration) error {
+	ring, err := parseType(t)
+	if err != nil {
+		return err
+	}
+	desc, err := description(id)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return errors.New("no key data given")
+	}
+	seconds := int(timeout.Seconds())
+	if seconds <= 0 {
+		return errors.New("keyring timeout must be > 0")
+	}
+	return set(ring, desc, data, seconds)
+}
+
+// Remove will purge a cached key from the keyring
+func Remove(t Type, id string) error {
+	ring, err := parseType(t)
+	if err != nil {
+		return err
+	}
+	desc, err := description(id)
+	if err != nil {
+		return err
+	}
+	return remove(ring, desc)
+}
@@ -0,0 +1,119 @@
+//go:build linux
+
+// Package keyring handles the linux kernel keyring via keyctl syscalls
+package keyring
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	keySpecSessionKeyring = -3
+	keySpecUserKeyring    = -4
+	keyctlSetPerm         = 5
+	keyctlSearch          = 10
+	keyctlRead            = 11
+	keyctlSetTimeout      = 15
+	keyctlInvalidate      = 21
+	keyType               = "user"
+	// possessor and user (same uid) have full access, nothing else
+	keyPerm = 0x3f3f0000
+)
+
+func keyring(t Type) int {
+	if t == SessionKeyring {
+		return keySpecSessionKeyring
+	}
+	return keySpecUserKeyring
+}
+
+func keyctl(cmd int, args ...uintptr) (int, error) {
+	var a [4]uintptr
+	copy(a[:], args)
+	r, _, errno := syscall.Syscall6(syscall.SYS_KEYCTL, uintptr(cmd), a[0], a[1], a[2], a[3], 0)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(r), nil
+}
+
+func search(t Type, desc string) (int, error) {
+	typ, err := syscall.BytePtrFromString(keyType)
+	if err != nil {
+		return -1, err
+	}
+	d, err := syscall.BytePtrFromString(desc)
+	if err != nil {
+		return -1, err
+	}
+	ring := keyring(t)
+	id, err := keyctl(keyctlSearch, uintptr(ring), uintptr(unsafe.Pointer(typ)), uintptr(unsafe.Pointer(d)), 0)
+	if err != nil {
+		if errors.Is(err, syscall.ENOKEY) || errors.Is(err, syscall.EKEYEXPIRED) || errors.Is(err, syscall.EKEYREVOKED) {
+			return -1, ErrNotFound
+		}
+		return -1, err
+	}
+	return id, nil
+}
+
+func get(t Type, desc string) ([]byte, error) {
+	id, err := search(t, desc)
+	if err != nil {
+		return nil, err
+	}
+	size, err := keyctl(keyctlRead, uintptr(id), 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	if size == 0 {
+		return nil, ErrNotFound
+	}
+	buf := make([]byte, size)
+	read, err := keyctl(keyctlRead, uintptr(id), uintptr(unsafe.Pointer(&buf[0])), uintptr(size))
+	if err != nil {
+		clear(buf)
+		return nil, err
+	}
+	if read > size {
+		read = size
+	}
+	return buf[:read], nil
+}
+
+func set(t Type, desc string, data []byte, timeout int) error {
+	typ, err := syscall.BytePtrFromString(keyType)
+	if err != nil {
+		return err
+	}
+	d, err := syscall.BytePtrFromString(desc)
+	if err != nil {
+		return err
+	}
+	ring := keyring(t)
+	r, _, errno := syscall.Syscall6(syscall.SYS_ADD_KEY, uintptr(unsafe.Pointer(typ)), uintptr(unsafe.Pointer(d)), uintptr(unsafe.Pointer(&data[0])), uintptr(len(data)), uintptr(ring), 0)
+	if errno != 0 {
+		return errno
+	}
+	id := uintptr(r)
+	if _, err := keyctl(keyctlSetPerm, id, keyPerm); err != nil {
+		keyctl(keyctlInvalidate, id)
+		return err
+	}
+	if _, err := keyctl(keyctlSetTimeout, id, uintptr(timeout)); err != nil {
+		keyctl(keyctlInvalidate, id)
+		return err
+	}
+	return nil
+}
+
+func remove(t Type, desc string) error {
+	id, err := search(t, desc)
+	if err != nil {
+		return err
+	}
+	_, err = keyctl(keyctlInvalidate, uintptr(id))
+	return err
+}
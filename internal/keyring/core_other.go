@@ -0,0 +1,16 @@
+//go:build !linux
+
+// Package keyring handles platforms without a kernel keyring
+package keyring
+
+func get(Type, string) ([]byte, error) {
+	return nil, ErrUnsupported
+}
+
+func set(Type, string, []byte, int) error {
+	return ErrUnsupported
+}
+
+func remove(Type, string) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,73 @@
+package keyring_test
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/enckse/lockbox/internal/keyring"
+)
+
+func TestErrors(t *testing.T) {
+	if _, err := keyring.Get("abc", "id"); err == nil || err.Error() != "unknown keyring: abc" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := keyring.Get(keyring.UserKeyring, ""); err == nil || err.Error() != "no key identifier given" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := keyring.Set(keyring.UserKeyring, "id", nil, time.Second); err == nil || err.Error() != "no key data given" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := keyring.Set(keyring.UserKeyring, "id", []byte("x"), 0); err == nil || err.Error() != "keyring timeout must be > 0" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := keyring.Remove("abc", "id"); err == nil || err.Error() != "unknown keyring: abc" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestTypes(t *testing.T) {
+	if v := keyring.Types(); len(v) != 2 || v[0] != "session" || v[1] != "user" {
+		t.Errorf("invalid types: %v", v)
+	}
+}
+
+func TestKeyring(t *testing.T) {
+	id := t.TempDir()
+	for _, ring := range []keyring.Type{keyring.UserKeyring, keyring.SessionKeyring} {
+		_, err := keyring.Get(ring, id)
+		if runtime.GOOS != "linux" {
+			if !errors.Is(err, keyring.ErrUnsupported) {
+				t.Errorf("invalid error: %v", err)
+			}
+			continue
+		}
+		if !errors.Is(err, keyring.ErrNotFound) {
+			t.Errorf("invalid error: %v", err)
+		}
+		if err := keyring.Set(ring, id, []byte("abc"), time.Minute); err != nil {
+			t.Errorf("invalid error: %v", err)
+		}
+		b, err := keyring.Get(ring, id)
+		if err != nil || string(b) != "abc" {
+			t.Errorf("invalid get: %s %v", string(b), err)
+		}
+		if err := keyring.Set(ring, id, []byte("xyz"), time.Minute); err != nil {
+			t.Errorf("invalid error: %v", err)
+		}
+		b, err = keyring.Get(ring, id)
+		if err != nil || string(b) != "xyz" {
+			t.Errorf("invalid get: %s %v", string(b), err)
+		}
+		if err := keyring.Remove(ring, id); err != nil {
+			t.Errorf("invalid error: %v", err)
+		}
+		if _, err := keyring.Get(ring, id); !errors.Is(err, keyring.ErrNotFound) {
+			t.Errorf("invalid error: %v", err)
+		}
+		if err := keyring.Remove(ring, id); !errors.Is(err, keyring.ErrNotFound) {
+			t.Errorf("invalid error: %v", err)
+		}
+	}
+}
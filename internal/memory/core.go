@@ -0,0 +1,60 @@
+// Package memory handles locked (non-swappable) memory for secrets
+package memory
+
+import (
+	"os"
+	"syscall"
+)
+
+// Buffer is a locked memory region holding a secret, it is zeroed when destroyed
+type Buffer struct {
+	region []byte
+	length int
+}
+
+// New will copy data into a new locked buffer
+func New(data []byte) (*Buffer, error) {
+	size := os.Getpagesize()
+	for size < len(data) {
+		size += os.Getpagesize()
+	}
+	region, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Mlock(region); err != nil {
+		syscall.Munmap(region)
+		return nil, err
+	}
+	copy(region, data)
+	return &Buffer{region: region, length: len(data)}, nil
+}
+
+// Bytes will get a copy of the buffer contents (the caller should clear it after use)
+func (b *Buffer) Bytes() []byte {
+	out := make([]byte, b.length)
+	copy(out, b.region[:b.length])
+	return out
+}
+
+// Equal will compare the buffer contents to a value (without copying the contents)
+func (b *Buffer) Equal(value string) bool {
+	return string(b.region[:b.length]) == value
+}
+
+// Len is the length of the buffer contents
+func (b *Buffer) Len() int {
+	return b.length
+}
+
+// Destroy will zero and release the buffer
+func (b *Buffer) Destroy() {
+	if b.region == nil {
+		return
+	}
+	clear(b.region)
+	syscall.Munlock(b.region)
+	syscall.Munmap(b.region)
+	b.region = nil
+	b.length = 0
+}
@@ -0,0 +1,38 @@
+package memory_test
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/enckse/lockbox/internal/memory"
+)
+
+func TestBuffer(t *testing.T) {
+	b, err := memory.New([]byte("secret"))
+	if err != nil {
+		t.Fatalf("invalid error: %v", err)
+	}
+	if b.Len() != 6 || string(b.Bytes()) != "secret" || !b.Equal("secret") || b.Equal("secrets") {
+		t.Errorf("invalid buffer: %d %s", b.Len(), string(b.Bytes()))
+	}
+	b.Destroy()
+	if b.Len() != 0 || len(b.Bytes()) != 0 || !b.Equal("") {
+		t.Error("buffer not destroyed")
+	}
+	b.Destroy()
+	b, err = memory.New(make([]byte, 10000))
+	if err != nil || b.Len() != 10000 {
+		t.Errorf("invalid buffer: %v", err)
+	}
+	b.Destroy()
+}
+
+func TestDisableCoreDumps(t *testing.T) {
+	if err := memory.DisableCoreDumps(); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_CORE, &limit); err != nil || limit.Cur != 0 || limit.Max != 0 {
+		t.Errorf("core dumps not disabled: %v %v", limit, err)
+	}
+}
@@ -0,0 +1,12 @@
+// Package memory handles disabling core dumps
+package memory
+
+import "syscall"
+
+// DisableCoreDumps will prevent the process from writing (secrets to) a core dump
+func DisableCoreDumps() error {
+	if err := syscall.Setrlimit(syscall.RLIMIT_CORE, &syscall.Rlimit{Cur: 0, Max: 0}); err != nil {
+		return err
+	}
+	return nonDumpable()
+}
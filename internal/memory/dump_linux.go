@@ -0,0 +1,14 @@
+//go:build linux
+
+// Package memory handles marking the process as non-dumpable
+package memory
+
+import "syscall"
+
+func nonDumpable() error {
+	// NOTE: also blocks ptrace attach (as the same user) and core dump handlers ignoring the rlimit
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, syscall.PR_SET_DUMPABLE, 0, 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
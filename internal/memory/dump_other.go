@@ -0,0 +1,8 @@
+//go:build !linux
+
+// Package memory handles platforms without process dumpable control
+package memory
+
+func nonDumpable() error {
+	return nil
+}
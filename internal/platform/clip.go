@@ -8,20 +8,47 @@ import (
 	"os/exec"
 	"runtime"
 	"strings"
+	"syscall"
 
 	"github.com/enckse/lockbox/internal/config"
 )
 
+const (
+	pbcopyProfile  = "pbcopy"
+	wslProfile     = "clip.exe"
+	waylandProfile = "wl-copy"
+	xclipProfile   = "xclip"
+	commandProfile = "command"
+)
+
 type (
 	// Clipboard represent system clipboard operations.
-	Clipboard []string
+	Clipboard struct {
+		copying []string
+		profile clipboardProfile
+		// MaxTime is how long (seconds) a copied value is left in the clipboard (0 will not clear)
+		MaxTime int64
+		// clearCommand is the (background) subcommand of this executable that clears the clipboard
+		clearCommand string
+		notice       string
+	}
+	// ClipboardPaste represents reading the system clipboard.
+	ClipboardPaste []string
 	// ClipboardLoader handles how the system is detected
 	ClipboardLoader interface {
 		Name() (string, error)
 		Runtime() string
+		// Help is the help output of a command (to detect supported flags)
+		Help(command string) (string, error)
 	}
 	// DefaultClipboardLoader is the default system detector
 	DefaultClipboardLoader struct{}
+	clipboardProfile       struct {
+		name      string
+		primary   bool
+		sensitive bool
+		command   []string
+	}
 )
 
 // Name will get the uname results
@@ -38,50 +65,319 @@ func (l DefaultClipboardLoader) Runtime() string {
 	return runtime.GOOS
 }
 
-// NewClipboard creates a new clipboard
-func NewClipboard(loader ClipboardLoader) (Clipboard, error) {
+// Help will get the '--help' output of a command
+func (l DefaultClipboardLoader) Help(command string) (string, error) {
+	b, err := exec.Command(command, "--help").CombinedOutput()
+	return string(b), err
+}
+
+// NewClipboard creates a new clipboard, copied values are cleared (when a timeout is set) by
+// running the clear command (a subcommand of this executable) in the background
+func NewClipboard(loader ClipboardLoader, clearCommand string) (Clipboard, error) {
 	if !config.EnvFeatureClip.Get() {
 		return Clipboard{}, config.NewFeatureError("clip")
 	}
-	overrideCopy := config.EnvClipCopy.Get()
-	if len(overrideCopy) > 0 {
-		return overrideCopy, nil
+	timeout, err := config.EnvClipTimeout.Get()
+	if err != nil {
+		return Clipboard{}, err
+	}
+	profile, err := loadProfile(loader, config.EnvClipCopy.Get())
+	if err != nil {
+		return Clipboard{}, err
+	}
+	notice := ""
+	if timeout > 0 {
+		// NOTE: the clipboard must be read back (checking it still holds the value) to clear it,
+		// osc52 can never be read (and the background clear has no terminal to write to)
+		reason := profile.unreadable()
+		if profile.name != config.OSC52ClipBackend && len(config.EnvClipPaste.Get()) > 0 {
+			reason = ""
+		}
+		if reason != "" {
+			timeout = 0
+			notice = fmt.Sprintf("clipboard will NOT be cleared (%s)", reason)
+		}
+	}
+	return Clipboard{copying: profile.copying(), profile: profile, MaxTime: timeout, clearCommand: clearCommand, notice: notice}, nil
+}
+
+// NewClipboardPaste creates a new clipboard reader
+func NewClipboardPaste(loader ClipboardLoader) (ClipboardPaste, error) {
+	if !config.EnvFeatureClip.Get() {
+		return ClipboardPaste{}, config.NewFeatureError("clip")
+	}
+	overridePaste := config.EnvClipPaste.Get()
+	if len(overridePaste) > 0 {
+		return overridePaste, nil
+	}
+	profile, err := loadProfile(loader, config.EnvClipCopy.Get())
+	if err != nil {
+		return ClipboardPaste{}, err
+	}
+	if reason := profile.unreadable(); reason != "" {
+		return ClipboardPaste{}, errors.New(reason)
+	}
+	return profile.pasting(), nil
+}
+
+func loadProfile(loader ClipboardLoader, overrideCopy []string) (clipboardProfile, error) {
+	profile := clipboardProfile{sensitive: config.EnvClipSensitive.Get()}
+	switch selection := config.EnvClipSelection.Get(); selection {
+	case config.ClipboardSelection:
+	case config.PrimarySelection:
+		profile.primary = true
+	default:
+		return clipboardProfile{}, fmt.Errorf("unknown clipboard selection: %s", selection)
+	}
+	switch mode := config.EnvClipBackend.Get(); mode {
+	case config.OSC52ClipBackend:
+		profile.name = config.OSC52ClipBackend
+	case config.DetectClipBackend:
+		if len(overrideCopy) > 0 {
+			return clipboardProfile{name: commandProfile, command: overrideCopy}, nil
+		}
+		name, err := detectProfile(loader)
+		if err != nil {
+			return clipboardProfile{}, err
+		}
+		profile.name = name
+		if name == waylandProfile && profile.sensitive {
+			// NOTE: older wl-copy builds do not have the flag (and fail to copy when given it)
+			help, err := loader.Help(waylandProfile)
+			profile.sensitive = err == nil && strings.Contains(help, "--sensitive")
+		}
+	default:
+		return clipboardProfile{}, fmt.Errorf("unknown clipboard backend: %s", mode)
 	}
+	return profile, nil
+}
 
+func detectProfile(loader ClipboardLoader) (string, error) {
 	switch loader.Runtime() {
 	case "darwin":
-		return []string{"pbcopy"}, nil
+		return pbcopyProfile, nil
 	case "linux":
 		name, err := loader.Name()
 		if err != nil {
-			return Clipboard{}, err
+			return "", err
 		}
 		if strings.Contains(strings.ToLower(name), "microsoft") {
-			return []string{"clip.exe"}, nil
+			return wslProfile, nil
 		}
 		if strings.TrimSpace(os.Getenv("WAYLAND_DISPLAY")) != "" {
-			return []string{"wl-copy"}, nil
+			return waylandProfile, nil
 		}
 		if strings.TrimSpace(os.Getenv("DISPLAY")) != "" {
-			return []string{"xclip"}, nil
+			return xclipProfile, nil
 		}
-		return Clipboard{}, errors.New("unable to detect linux clipboard")
+		// NOTE: headless (e.g. ssh), use the terminal
+		return config.OSC52ClipBackend, nil
 	default:
-		return Clipboard{}, errors.New("clipboard is unavailable")
+		return "", errors.New("clipboard is unavailable")
+	}
+}
+
+func (p clipboardProfile) selection() string {
+	if p.primary {
+		return config.PrimarySelection
+	}
+	return config.ClipboardSelection
+}
+
+// copying gets the copy command (and arguments) for the profile
+func (p clipboardProfile) copying() []string {
+	switch p.name {
+	case pbcopyProfile:
+		return []string{"pbcopy"}
+	case wslProfile:
+		return []string{"clip.exe"}
+	case waylandProfile:
+		args := []string{"wl-copy"}
+		if p.primary {
+			args = append(args, "--primary")
+		}
+		if p.sensitive {
+			args = append(args, "--sensitive")
+		}
+		return args
+	case commandProfile:
+		return p.command
+	case xclipProfile:
+		// NOTE: xclip offers a single target (text), it can not also offer the sensitive hint
+		return []string{"xclip", "-selection", p.selection()}
+	}
+	return nil
+}
+
+// unreadable is why the profile's clipboard can not be read back (empty if it can), an
+// overridden copy command needs a paste command that reads the same clipboard
+func (p clipboardProfile) unreadable() string {
+	if p.name == commandProfile {
+		return "a paste command must be set when the copy command is overridden"
+	}
+	if len(p.pasting()) == 0 {
+		return fmt.Sprintf("%s clipboard can not be read", p.name)
+	}
+	return ""
+}
+
+// pasting gets the paste command (and arguments) for the profile
+func (p clipboardProfile) pasting() []string {
+	switch p.name {
+	case pbcopyProfile:
+		return []string{"pbpaste"}
+	case wslProfile:
+		return []string{"powershell.exe", "-NoProfile", "-Command", "Get-Clipboard"}
+	case waylandProfile:
+		args := []string{"wl-paste", "-n"}
+		if p.primary {
+			args = append(args, "--primary")
+		}
+		return args
+	case xclipProfile:
+		return []string{"xclip", "-o", "-selection", p.selection()}
+	}
+	return nil
+}
+
+// Notice is why copied values are not cleared, even though a timeout is set (empty otherwise)
+func (c Clipboard) Notice() string {
+	return c.notice
+}
+
+// String will get the clipboard backend (command) information
+func (c Clipboard) String() string {
+	if c.profile.name == config.OSC52ClipBackend {
+		return fmt.Sprintf("[%s]", config.OSC52ClipBackend)
+	}
+	return fmt.Sprintf("%v", c.copying)
+}
+
+// Profile will describe the clipboard backend and the profile (options) in use
+func (c Clipboard) Profile() string {
+	var options []string
+	switch c.profile.name {
+	case waylandProfile, xclipProfile, config.OSC52ClipBackend:
+		options = append(options, c.profile.selection())
+	}
+	if c.profile.name == waylandProfile && c.profile.sensitive {
+		options = append(options, "sensitive")
+	}
+	if len(options) == 0 {
+		return c.profile.name
 	}
+	return fmt.Sprintf("%s (%s)", c.profile.name, strings.Join(options, ", "))
 }
 
 // CopyTo will copy to clipboard, if non-empty will clear later.
 func (c Clipboard) CopyTo(value string) error {
-	if len(c) == 0 {
+	if c.profile.name == config.OSC52ClipBackend {
+		return copyOSC52(value, c.profile.primary)
+	}
+	if len(c.copying) == 0 {
 		return errors.New("copy command is not set")
 	}
-	cmd := c[0]
+	cmd := c.copying[0]
 	var args []string
-	if len(c) > 1 {
-		args = c[1:]
+	if len(c.copying) > 1 {
+		args = c.copying[1:]
+	}
+	if err := pipeTo(cmd, value, args...); err != nil {
+		return err
+	}
+	if value == "" || c.MaxTime == 0 {
+		return nil
+	}
+	if c.clearCommand == "" {
+		return errors.New("clear command is not set")
+	}
+	return clearLater(c.clearCommand, value)
+}
+
+// CopyOnce will copy to the clipboard (without clearing) and wait for the value to be pasted
+// (when the backend can detect a paste) or for the next signal on next, false is returned
+// (without waiting any longer) when next is closed
+func (c Clipboard) CopyOnce(value string, next <-chan struct{}) (bool, error) {
+	var once []string
+	switch c.profile.name {
+	case waylandProfile:
+		once = append(c.profile.copying(), "--foreground", "--paste-once")
+	case xclipProfile:
+		once = append(c.profile.copying(), "-quiet", "-loops", "1")
+	}
+	if len(once) == 0 {
+		c.MaxTime = 0
+		if err := c.CopyTo(value); err != nil {
+			return false, err
+		}
+		_, ok := <-next
+		return ok, nil
+	}
+	cmd := exec.Command(once[0], once[1:]...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return false, err
+	}
+	if err := cmd.Start(); err != nil {
+		return false, err
+	}
+	if _, err := stdin.Write([]byte(value)); err != nil {
+		return false, err
+	}
+	if err := stdin.Close(); err != nil {
+		return false, err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		return err == nil, err
+	case _, ok := <-next:
+		if err := cmd.Process.Kill(); err != nil {
+			return false, err
+		}
+		<-done
+		return ok, nil
+	}
+}
+
+// Read will read the current clipboard value
+func (c ClipboardPaste) Read() (string, error) {
+	if len(c) == 0 {
+		return "", errors.New("paste command is not set")
+	}
+	b, err := exec.Command(c[0], c[1:]...).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// clearLater will start a detached process that clears the clipboard (after the timeout)
+func clearLater(command, value string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(exe, command)
+	// NOTE: a new session so the clear survives the terminal closing
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if _, err := stdin.Write([]byte(value)); err != nil {
+		return err
+	}
+	if err := stdin.Close(); err != nil {
+		return err
 	}
-	return pipeTo(cmd, value, args...)
+	return cmd.Process.Release()
 }
 
 func pipeTo(command, value string, args ...string) error {
@@ -2,6 +2,7 @@ package platform_test
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/enckse/lockbox/internal/config/store"
@@ -12,6 +13,7 @@ type mockLoader struct {
 	err     error
 	name    string
 	runtime string
+	oldCopy bool
 }
 
 func (m mockLoader) Name() (string, error) {
@@ -22,10 +24,17 @@ func (m mockLoader) Runtime() string {
 	return m.runtime
 }
 
+func (m mockLoader) Help(string) (string, error) {
+	if m.oldCopy {
+		return "Usage: wl-copy [options] text to copy", nil
+	}
+	return "Usage: wl-copy [options] text to copy\n  --sensitive", nil
+}
+
 func TestDisabled(t *testing.T) {
 	defer store.Clear()
 	store.SetBool("LOCKBOX_FEATURE_CLIP", false)
-	if _, err := platform.NewClipboard(mockLoader{}); err == nil || err.Error() != "clip feature is disabled" {
+	if _, err := platform.NewClipboard(mockLoader{}, "clip-clear"); err == nil || err.Error() != "clip feature is disabled" {
 		t.Errorf("invalid error: %v", err)
 	}
 }
@@ -35,7 +44,7 @@ func TestInstance(t *testing.T) {
 	defer store.Clear()
 	fxn := func(runtime, name, c, e string) {
 		l := mockLoader{runtime: runtime, name: name}
-		b, err := platform.NewClipboard(l)
+		b, err := platform.NewClipboard(l, "clip-clear")
 		if err != nil {
 			if err.Error() != e {
 				t.Errorf("invalid error: %v", err)
@@ -50,23 +59,23 @@ func TestInstance(t *testing.T) {
 	fxn("linux", "microsoft", "[clip.exe]", "")
 	t.Setenv("DISPLAY", "")
 	t.Setenv("WAYLAND_DISPLAY", "")
-	fxn("linux", "linux", "", "unable to detect linux clipboard")
+	fxn("linux", "linux", "[osc52]", "")
 	t.Setenv("DISPLAY", "1")
 	t.Setenv("WAYLAND_DISPLAY", "1")
-	fxn("linux", "linux", "[wl-copy]", "")
+	fxn("linux", "linux", "[wl-copy --sensitive]", "")
 	t.Setenv("WAYLAND_DISPLAY", "")
-	fxn("linux", "linux", "[xclip]", "")
+	fxn("linux", "linux", "[xclip -selection clipboard]", "")
 }
 
 func TestCopy(t *testing.T) {
 	store.Clear()
 	defer store.Clear()
 	store.SetArray("LOCKBOX_CLIP_COPY", []string{})
-	if _, err := platform.NewClipboard(mockLoader{}); err == nil || err.Error() != "clipboard is unavailable" {
+	if _, err := platform.NewClipboard(mockLoader{}, "clip-clear"); err == nil || err.Error() != "clipboard is unavailable" {
 		t.Errorf("invalid error: %v", err)
 	}
 	store.SetArray("LOCKBOX_CLIP_COPY", []string{"x"})
-	c, err := platform.NewClipboard(mockLoader{name: "microsoft", runtime: "linux"})
+	c, err := platform.NewClipboard(mockLoader{name: "microsoft", runtime: "linux"}, "clip-clear")
 	if err != nil {
 		t.Errorf("invalid error: %v", err)
 	}
@@ -74,7 +83,7 @@ func TestCopy(t *testing.T) {
 		t.Errorf("invalid override: %v", c)
 	}
 	store.SetArray("LOCKBOX_CLIP_COPY", []string{"x", "y", "z"})
-	c, err = platform.NewClipboard(mockLoader{name: "microsoft", runtime: "linux"})
+	c, err = platform.NewClipboard(mockLoader{name: "microsoft", runtime: "linux"}, "clip-clear")
 	if err != nil {
 		t.Errorf("invalid error: %v", err)
 	}
@@ -85,8 +94,153 @@ func TestCopy(t *testing.T) {
 	if err := c.CopyTo(""); err == nil || err.Error() != "copy command is not set" {
 		t.Errorf("invalid error: %v", err)
 	}
-	c = platform.Clipboard{"echo"}
+	store.SetArray("LOCKBOX_CLIP_COPY", []string{"echo"})
+	c, _ = platform.NewClipboard(mockLoader{}, "clip-clear")
 	if err := c.CopyTo(""); err != nil {
 		t.Errorf("invalid error: %v", err)
 	}
 }
+
+func TestPaste(t *testing.T) {
+	store.Clear()
+	defer store.Clear()
+	fxn := func(runtime, name, c, e string) {
+		p, err := platform.NewClipboardPaste(mockLoader{runtime: runtime, name: name})
+		if err != nil {
+			if err.Error() != e {
+				t.Errorf("invalid error: %v", err)
+			}
+			return
+		}
+		if fmt.Sprintf("%v", p) != c {
+			t.Errorf("invalid paste: %s != %v", c, p)
+		}
+	}
+	fxn("darwin", "", "[pbpaste]", "")
+	fxn("linux", "microsoft", "[powershell.exe -NoProfile -Command Get-Clipboard]", "")
+	t.Setenv("DISPLAY", "")
+	t.Setenv("WAYLAND_DISPLAY", "")
+	fxn("linux", "linux", "", "osc52 clipboard can not be read")
+	t.Setenv("DISPLAY", "1")
+	t.Setenv("WAYLAND_DISPLAY", "1")
+	fxn("linux", "linux", "[wl-paste -n]", "")
+	t.Setenv("WAYLAND_DISPLAY", "")
+	fxn("linux", "linux", "[xclip -o -selection clipboard]", "")
+	store.SetArray("LOCKBOX_CLIP_PASTE", []string{"/bin/echo", "value"})
+	fxn("linux", "linux", "[/bin/echo value]", "")
+	p, _ := platform.NewClipboardPaste(mockLoader{})
+	if v, err := p.Read(); err != nil || v != "value" {
+		t.Errorf("invalid read: %s %v", v, err)
+	}
+	if _, err := (platform.ClipboardPaste{}).Read(); err == nil || err.Error() != "paste command is not set" {
+		t.Errorf("invalid error: %v", err)
+	}
+	store.SetBool("LOCKBOX_FEATURE_CLIP", false)
+	if _, err := platform.NewClipboardPaste(mockLoader{}); err == nil || err.Error() != "clip feature is disabled" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestBackend(t *testing.T) {
+	store.Clear()
+	defer store.Clear()
+	t.Setenv("DISPLAY", "1")
+	store.SetArray("LOCKBOX_CLIP_COPY", []string{"x"})
+	store.SetString("LOCKBOX_CLIP_BACKEND", "osc52")
+	c, err := platform.NewClipboard(mockLoader{runtime: "linux"}, "clip-clear")
+	if err != nil || fmt.Sprintf("%v", c) != "[osc52]" || c.MaxTime != 0 || c.Notice() != "clipboard will NOT be cleared (osc52 clipboard can not be read)" {
+		t.Errorf("invalid clipboard: %v %v", c, err)
+	}
+	store.SetString("LOCKBOX_CLIP_BACKEND", "other")
+	if _, err := platform.NewClipboard(mockLoader{runtime: "linux"}, "clip-clear"); err == nil || err.Error() != "unknown clipboard backend: other" {
+		t.Errorf("invalid error: %v", err)
+	}
+	store.SetString("LOCKBOX_CLIP_BACKEND", "detect")
+	c, err = platform.NewClipboard(mockLoader{runtime: "linux"}, "clip-clear")
+	if err != nil || fmt.Sprintf("%v", c) != "[x]" || c.MaxTime != 0 || c.Notice() != "clipboard will NOT be cleared (a paste command must be set when the copy command is overridden)" {
+		t.Errorf("invalid clipboard: %v %v", c, err)
+	}
+	if _, err := platform.NewClipboardPaste(mockLoader{runtime: "linux"}); err == nil || err.Error() != "a paste command must be set when the copy command is overridden" {
+		t.Errorf("invalid error: %v", err)
+	}
+	store.SetArray("LOCKBOX_CLIP_PASTE", []string{"y"})
+	c, err = platform.NewClipboard(mockLoader{runtime: "linux"}, "clip-clear")
+	if err != nil || fmt.Sprintf("%v", c) != "[x]" || c.MaxTime != 45 || c.Notice() != "" {
+		t.Errorf("invalid clipboard: %v %v", c, err)
+	}
+	store.SetArray("LOCKBOX_CLIP_COPY", []string{"true"})
+	c, _ = platform.NewClipboard(mockLoader{runtime: "linux"}, "")
+	if err := c.CopyTo("value"); err == nil || err.Error() != "clear command is not set" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestOSC52(t *testing.T) {
+	t.Setenv("TMUX", "")
+	t.Setenv("STY", "")
+	t.Setenv("TERM", "xterm")
+	if s := platform.OSC52("test", false); s != "\x1b]52;c;dGVzdA==\x07" {
+		t.Errorf("invalid sequence: %q", s)
+	}
+	if s := platform.OSC52("test", true); s != "\x1b]52;p;dGVzdA==\x07" {
+		t.Errorf("invalid sequence: %q", s)
+	}
+	t.Setenv("TMUX", "/tmp/tmux")
+	if s := platform.OSC52("test", false); s != "\x1bPtmux;\x1b\x1b]52;c;dGVzdA==\x07\x1b\\" {
+		t.Errorf("invalid sequence: %q", s)
+	}
+	t.Setenv("TMUX", "")
+	t.Setenv("TERM", "screen-256color")
+	if s := platform.OSC52("test", false); s != "\x1bP\x1b]52;c;dGVzdA==\x07\x1b\\" {
+		t.Errorf("invalid sequence: %q", s)
+	}
+	s := platform.OSC52(strings.Repeat("a", 100), false)
+	if strings.Count(s, "\x1bP") != 2 || !strings.HasSuffix(s, "\x07\x1b\\") {
+		t.Errorf("invalid sequence: %q", s)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	store.Clear()
+	defer store.Clear()
+	t.Setenv("DISPLAY", "1")
+	t.Setenv("WAYLAND_DISPLAY", "1")
+	fxn := func(c, p, e string) {
+		b, err := platform.NewClipboard(mockLoader{runtime: "linux", name: "linux"}, "clip-clear")
+		if err != nil {
+			if err.Error() != e {
+				t.Errorf("invalid error: %v", err)
+			}
+			return
+		}
+		if fmt.Sprintf("%v", b) != c || b.Profile() != p {
+			t.Errorf("invalid profile: %v %s", b, b.Profile())
+		}
+	}
+	fxn("[wl-copy --sensitive]", "wl-copy (clipboard, sensitive)", "")
+	if b, err := platform.NewClipboard(mockLoader{runtime: "linux", name: "linux", oldCopy: true}, "clip-clear"); err != nil || fmt.Sprintf("%v", b) != "[wl-copy]" || b.Profile() != "wl-copy (clipboard)" {
+		t.Errorf("invalid profile: %v %s %v", b, b.Profile(), err)
+	}
+	store.SetString("LOCKBOX_CLIP_SELECTION", "primary")
+	fxn("[wl-copy --primary --sensitive]", "wl-copy (primary, sensitive)", "")
+	store.SetBool("LOCKBOX_CLIP_SENSITIVE", false)
+	fxn("[wl-copy --primary]", "wl-copy (primary)", "")
+	t.Setenv("WAYLAND_DISPLAY", "")
+	fxn("[xclip -selection primary]", "xclip (primary)", "")
+	store.SetString("LOCKBOX_CLIP_BACKEND", "osc52")
+	fxn("[osc52]", "osc52 (primary)", "")
+	store.SetString("LOCKBOX_CLIP_SELECTION", "other")
+	fxn("", "", "unknown clipboard selection: other")
+	store.Clear()
+	store.SetArray("LOCKBOX_CLIP_COPY", []string{"x", "y"})
+	fxn("[x y]", "command", "")
+	p, err := platform.NewClipboard(mockLoader{runtime: "darwin"}, "clip-clear")
+	if err != nil || p.Profile() != "command" {
+		t.Errorf("invalid profile: %s %v", p.Profile(), err)
+	}
+	store.Clear()
+	p, _ = platform.NewClipboard(mockLoader{runtime: "darwin"}, "clip-clear")
+	if p.Profile() != "pbcopy" {
+		t.Errorf("invalid profile: %s", p.Profile())
+	}
+}
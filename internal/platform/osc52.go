@@ -0,0 +1,53 @@
+// Package platform handles OSC 52 (terminal escape sequence) clipboard operations
+package platform
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	osc52Start = "\x1b]52;"
+	osc52End   = "\x07"
+	dcsStart   = "\x1bP"
+	dcsEnd     = "\x1b\\"
+	// NOTE: screen limits the length of a passthrough (DCS) string
+	screenChunk = 76
+	terminal    = "/dev/tty"
+)
+
+// OSC52 will create the OSC 52 sequence to set the clipboard/primary selection (wrapped for tmux/screen passthrough)
+func OSC52(value string, primary bool) string {
+	selection := "c"
+	if primary {
+		selection = "p"
+	}
+	sequence := osc52Start + selection + ";" + base64.StdEncoding.EncodeToString([]byte(value)) + osc52End
+	if strings.TrimSpace(os.Getenv("TMUX")) != "" {
+		return dcsStart + "tmux;" + strings.ReplaceAll(sequence, "\x1b", "\x1b\x1b") + dcsEnd
+	}
+	if strings.TrimSpace(os.Getenv("STY")) != "" || strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		var b strings.Builder
+		for len(sequence) > 0 {
+			size := min(len(sequence), screenChunk)
+			b.WriteString(dcsStart + sequence[:size] + dcsEnd)
+			sequence = sequence[size:]
+		}
+		return b.String()
+	}
+	return sequence
+}
+
+func copyOSC52(value string, primary bool) error {
+	f, err := os.OpenFile(terminal, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("unable to open terminal for osc52: %w", err)
+	}
+	if _, err := f.WriteString(OSC52(value, primary)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
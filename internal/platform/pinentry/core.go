@@ -0,0 +1,170 @@
+// Package pinentry handles talking the assuan protocol to a pinentry program
+package pinentry
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultCommand is the pinentry program used when none is given
+	DefaultCommand = "pinentry"
+	okResponse     = "OK"
+	errResponse    = "ERR"
+	dataResponse   = "D"
+)
+
+type (
+	// Request is what is displayed to the user by the pinentry program
+	Request struct {
+		Title       string
+		Description string
+		Prompt      string
+		Options     []string
+	}
+	session struct {
+		reader *bufio.Reader
+		writer io.Writer
+	}
+)
+
+// GetPIN will run the pinentry program and request the PIN/password
+func GetPIN(command []string, req Request) (string, error) {
+	if len(command) == 0 {
+		command = []string{DefaultCommand}
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return "", err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("unable to start pinentry: %w", err)
+	}
+	s := session{reader: bufio.NewReader(stdout), writer: stdin}
+	pin, err := s.run(req)
+	stdin.Close()
+	waitErr := cmd.Wait()
+	if err != nil {
+		return "", err
+	}
+	if waitErr != nil {
+		return "", fmt.Errorf("pinentry failed: %w", waitErr)
+	}
+	return pin, nil
+}
+
+func (s session) run(req Request) (string, error) {
+	if _, err := s.read(); err != nil {
+		return "", err
+	}
+	for _, option := range req.Options {
+		// NOTE: options are hints, pinentry programs may not support all of them
+		if _, err := s.send("OPTION", option); err != nil {
+			var protocol protocolError
+			if !errors.As(err, &protocol) {
+				return "", err
+			}
+		}
+	}
+	for _, item := range []struct {
+		command string
+		value   string
+	}{
+		{"SETTITLE", req.Title},
+		{"SETDESC", req.Description},
+		{"SETPROMPT", req.Prompt},
+	} {
+		if item.value == "" {
+			continue
+		}
+		if _, err := s.send(item.command, item.value); err != nil {
+			return "", err
+		}
+	}
+	pin, err := s.send("GETPIN", "")
+	if err != nil {
+		return "", err
+	}
+	s.send("BYE", "")
+	return pin, nil
+}
+
+type protocolError struct {
+	message string
+}
+
+func (e protocolError) Error() string {
+	return fmt.Sprintf("pinentry error: %s", e.message)
+}
+
+func (s session) send(command, arg string) (string, error) {
+	line := command
+	if arg != "" {
+		line = fmt.Sprintf("%s %s", command, Escape(arg))
+	}
+	if _, err := fmt.Fprintf(s.writer, "%s\n", line); err != nil {
+		return "", err
+	}
+	return s.read()
+}
+
+func (s session) read() (string, error) {
+	var data strings.Builder
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			return "", fmt.Errorf("pinentry closed unexpectedly: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command, rest, _ := strings.Cut(line, " ")
+		switch command {
+		case okResponse:
+			return data.String(), nil
+		case errResponse:
+			return "", protocolError{message: rest}
+		case dataResponse:
+			data.WriteString(Unescape(rest))
+		}
+	}
+}
+
+// Escape will percent-escape values for the assuan protocol
+func Escape(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		switch r {
+		case '%', '\r', '\n':
+			fmt.Fprintf(&b, "%%%02X", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Unescape will decode percent-escaped assuan values
+func Unescape(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c == '%' && i+2 < len(value) {
+			if decoded, err := strconv.ParseUint(value[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(decoded))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
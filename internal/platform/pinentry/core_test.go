@@ -0,0 +1,100 @@
+package pinentry_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/enckse/lockbox/internal/platform/pinentry"
+)
+
+const script = `#!/bin/sh
+echo "# stand-in pinentry"
+echo "OK Pleased to meet you"
+while read -r cmd rest; do
+  echo "$cmd $rest" >> "$0.log"
+  case "$cmd" in
+    OPTION)
+      if [ "$rest" = "unknown" ]; then
+        echo "ERR 83886254 Unknown option"
+      else
+        echo "OK"
+      fi
+      ;;
+    GETPIN)
+      %s
+      ;;
+    BYE)
+      echo "OK closing connection"
+      exit 0
+      ;;
+    *)
+      echo "S some status"
+      echo "OK"
+      ;;
+  esac
+done
+`
+
+func newPinentry(t *testing.T, getpin string) string {
+	path := filepath.Join(t.TempDir(), "pinentry")
+	if err := os.WriteFile(path, fmt.Appendf(nil, script, getpin), 0o700); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+	return path
+}
+
+func TestGetPIN(t *testing.T) {
+	p := newPinentry(t, `echo "D pass%25wo"; echo "D rd%0A"; echo "OK"`)
+	pin, err := pinentry.GetPIN([]string{p}, pinentry.Request{Title: "title", Description: "a\nb%", Prompt: "Password:", Options: []string{"unknown", "ttyname=/dev/pts/1"}})
+	if err != nil || pin != "pass%word\n" {
+		t.Errorf("invalid pin: %s %v", pin, err)
+	}
+	b, err := os.ReadFile(p + ".log")
+	if err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	expect := "OPTION unknown\nOPTION ttyname=/dev/pts/1\nSETTITLE title\nSETDESC a%0Ab%25\nSETPROMPT Password:\nGETPIN \nBYE \n"
+	if string(b) != expect {
+		t.Errorf("invalid session: %s", string(b))
+	}
+}
+
+func TestCancel(t *testing.T) {
+	p := newPinentry(t, `echo "ERR 83886179 Operation cancelled <Pinentry>"`)
+	if _, err := pinentry.GetPIN([]string{p}, pinentry.Request{}); err == nil || err.Error() != "pinentry error: 83886179 Operation cancelled <Pinentry>" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestFailures(t *testing.T) {
+	if _, err := pinentry.GetPIN([]string{filepath.Join(t.TempDir(), "missing")}, pinentry.Request{}); err == nil || !strings.HasPrefix(err.Error(), "unable to start pinentry") {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := pinentry.GetPIN([]string{"/bin/sh", "-c", "echo OK"}, pinentry.Request{}); err == nil || !strings.HasPrefix(err.Error(), "pinentry closed unexpectedly") {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	for k, v := range map[string]string{
+		"":        "",
+		"abc":     "abc",
+		"a%b":     "a%25b",
+		"a\r\nb":  "a%0D%0Ab",
+		"%%":      "%25%25",
+		"unicode": "unicode",
+	} {
+		if e := pinentry.Escape(k); e != v {
+			t.Errorf("invalid escape: %s != %s", e, v)
+		}
+		if u := pinentry.Unescape(v); u != k {
+			t.Errorf("invalid unescape: %s != %s", u, k)
+		}
+	}
+	if u := pinentry.Unescape("%zz%2"); u != "%zz%2" {
+		t.Errorf("invalid unescape: %s", u)
+	}
+}
@@ -0,0 +1,137 @@
+// Package tty handles terminal operations
+package tty
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+const device = "/dev/tty"
+
+type rawTerminal struct {
+	*os.File
+	signals chan os.Signal
+	done    chan struct{}
+	stop    sync.Once
+}
+
+// Echo will enable/disable echoing on the terminal connected to the file
+func Echo(f *os.File, on bool) error {
+	cmd := "echo"
+	if !on {
+		cmd = "-echo"
+	}
+	return stty(f, cmd)
+}
+
+func stty(f *os.File, args ...string) error {
+	// Common settings and variables for both stty calls.
+	attrs := syscall.ProcAttr{
+		Dir:   "",
+		Env:   []string{},
+		Files: []uintptr{f.Fd(), os.Stdout.Fd(), os.Stderr.Fd()},
+		Sys:   nil,
+	}
+	var ws syscall.WaitStatus
+
+	// Change the terminal settings.
+	pid, err := syscall.ForkExec(
+		"/bin/stty",
+		append([]string{"stty"}, args...),
+		&attrs)
+	if err != nil {
+		return err
+	}
+
+	// Wait for the stty process to complete.
+	_, err = syscall.Wait4(pid, &ws, 0, nil)
+	return err
+}
+
+// Keys will read keypresses (without waiting for enter or echoing) from the controlling terminal,
+// closing the reader restores the terminal
+func Keys() (io.ReadCloser, error) {
+	f, err := os.OpenFile(device, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open terminal: %w", err)
+	}
+	return RawKeys(f)
+}
+
+// RawKeys will read keypresses from a terminal, the terminal is restored when the reader is
+// closed or when the process is interrupted/terminated (which then exits as it would have)
+func RawKeys(f *os.File) (io.ReadCloser, error) {
+	if err := stty(f, "-icanon", "-echo", "min", "1"); err != nil {
+		f.Close()
+		return nil, err
+	}
+	r := &rawTerminal{File: f, signals: make(chan os.Signal, 1), done: make(chan struct{})}
+	signal.Notify(r.signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	go r.restoreOnSignal()
+	return r, nil
+}
+
+func (r *rawTerminal) restore() error {
+	return stty(r.File, "icanon", "echo")
+}
+
+func (r *rawTerminal) restoreOnSignal() {
+	select {
+	case sig := <-r.signals:
+		r.restore()
+		signal.Stop(r.signals)
+		// NOTE: the handler is gone, re-raise the signal to exit as the process would have
+		if s, ok := sig.(syscall.Signal); ok {
+			syscall.Kill(os.Getpid(), s)
+		}
+	case <-r.done:
+	}
+}
+
+// Close will restore the terminal and close it
+func (r *rawTerminal) Close() error {
+	r.stop.Do(func() {
+		signal.Stop(r.signals)
+		close(r.done)
+	})
+	err := r.restore()
+	if closeErr := r.File.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// ReadSecret will prompt for a secret on the controlling terminal (not stdin) with echo disabled
+func ReadSecret(prompt string) (string, error) {
+	f, err := os.OpenFile(device, os.O_RDWR, 0)
+	if err != nil {
+		return "", fmt.Errorf("unable to open terminal: %w", err)
+	}
+	defer f.Close()
+	return ReadSecretFrom(f, prompt)
+}
+
+// ReadSecretFrom will prompt for a secret on a terminal with echo disabled (restored after reading)
+func ReadSecretFrom(f *os.File, prompt string) (string, error) {
+	if _, err := fmt.Fprint(f, prompt); err != nil {
+		return "", err
+	}
+	if err := Echo(f, false); err != nil {
+		return "", err
+	}
+	defer func() {
+		Echo(f, true)
+		fmt.Fprintln(f)
+	}()
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
@@ -0,0 +1,120 @@
+package tty_test
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/enckse/lockbox/internal/platform/tty"
+)
+
+const helperEnv = "LOCKBOX_TTY_TEST_HELPER"
+
+func TestRawKeys(t *testing.T) {
+	// NOTE: stty complains about the pipe (not a terminal) on stderr
+	stderr := os.Stderr
+	os.Stderr, _ = os.Open(os.DevNull)
+	defer func() {
+		os.Stderr.Close()
+		os.Stderr = stderr
+	}()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("invalid pipe: %v", err)
+	}
+	defer w.Close()
+	keys, err := tty.RawKeys(r)
+	if err != nil {
+		t.Fatalf("invalid error: %v", err)
+	}
+	w.WriteString("ab")
+	b := make([]byte, 2)
+	if _, err := io.ReadFull(keys, b); err != nil || string(b) != "ab" {
+		t.Errorf("invalid keys: %s %v", string(b), err)
+	}
+	if err := keys.Close(); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := keys.Close(); err == nil {
+		t.Error("already closed")
+	}
+}
+
+func TestRawKeysSignal(t *testing.T) {
+	if os.Getenv(helperEnv) != "" {
+		r, _, err := os.Pipe()
+		if err != nil {
+			os.Exit(1)
+		}
+		if _, err := tty.RawKeys(r); err != nil {
+			os.Exit(1)
+		}
+		os.Stdout.WriteString("ready\n")
+		select {}
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^TestRawKeysSignal$")
+	cmd.Env = append(os.Environ(), helperEnv+"=1")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("invalid error: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("invalid error: %v", err)
+	}
+	if line, err := bufio.NewReader(stdout).ReadString('\n'); err != nil || line != "ready\n" {
+		cmd.Process.Kill()
+		t.Fatalf("helper not ready: %s %v", line, err)
+	}
+	cmd.Process.Signal(syscall.SIGTERM)
+	err = cmd.Wait()
+	status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	if err == nil || !ok || !status.Signaled() || status.Signal() != syscall.SIGTERM {
+		t.Errorf("helper did not exit on signal: %v", err)
+	}
+	// NOTE: stty (on a pipe) complains once entering raw mode and once restoring
+	if count := strings.Count(stderr.String(), "stty"); count != 2 {
+		t.Errorf("terminal not restored: %s", stderr.String())
+	}
+}
+
+func TestReadSecretFrom(t *testing.T) {
+	stderr := os.Stderr
+	os.Stderr, _ = os.Open(os.DevNull)
+	defer func() {
+		os.Stderr.Close()
+		os.Stderr = stderr
+	}()
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatalf("invalid socketpair: %v", err)
+	}
+	f := os.NewFile(uintptr(fds[0]), "terminal")
+	defer f.Close()
+	peer := os.NewFile(uintptr(fds[1]), "peer")
+	defer peer.Close()
+	peer.WriteString("  secret \n")
+	val, err := tty.ReadSecretFrom(f, "prompt: ")
+	if err != nil || val != "secret" {
+		t.Errorf("invalid secret: %s %v", val, err)
+	}
+	b := make([]byte, len("prompt: \n"))
+	if _, err := io.ReadFull(peer, b); err != nil || string(b) != "prompt: \n" {
+		t.Errorf("invalid prompt: %s %v", string(b), err)
+	}
+	peer.WriteString("partial")
+	syscall.Shutdown(fds[1], syscall.SHUT_WR)
+	val, err = tty.ReadSecretFrom(f, "")
+	if err != nil || val != "partial" {
+		t.Errorf("invalid secret: %s %v", val, err)
+	}
+	if _, err := tty.ReadSecretFrom(f, ""); err != io.EOF {
+		t.Errorf("invalid error: %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+// Package qr handles encoding (and rendering) QR codes in-process
+package qr
+
+import (
+	"bufio"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+const (
+	minVersion = 1
+	maxVersion = 40
+	// quietZone is the light border (in modules) required around a code
+	quietZone = 4
+	// error correction levels (table index), codes are encoded with M (~15% recovery)
+	levelL = 0
+	levelM = 1
+	levelQ = 2
+	levelH = 3
+	// gfPoly is the QR code GF(256) reducing polynomial
+	gfPoly        = 0x11d
+	formatMask    = 0x5412
+	formatPoly    = 0x537
+	versionPoly   = 0x1f25
+	modeByte      = 0x4
+	padByteFirst  = 0xec
+	padByteSecond = 0x11
+	// the terminal colors (white on black) so a code is readable regardless of theme
+	ansiStart = "\x1b[97;40m"
+	ansiEnd   = "\x1b[0m"
+)
+
+var (
+	eccPerBlock = [4][maxVersion + 1]int{
+		{-1, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+		{-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+		{-1, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+		{-1, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	}
+	eccBlocks = [4][maxVersion + 1]int{
+		{-1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+		{-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+		{-1, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+		{-1, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+	}
+	// formatLevels are the format information bits of each level
+	formatLevels = [4]int{1, 0, 3, 2}
+	expTable     [512]byte
+	logTable     [256]byte
+	// masks are the 8 data masks, keyed by mask pattern
+	masks = [8]func(x, y int) bool{
+		func(x, y int) bool { return (x+y)%2 == 0 },
+		func(_, y int) bool { return y%2 == 0 },
+		func(x, _ int) bool { return x%3 == 0 },
+		func(x, y int) bool { return (x+y)%3 == 0 },
+		func(x, y int) bool { return (x/3+y/2)%2 == 0 },
+		func(x, y int) bool { return x*y%2+x*y%3 == 0 },
+		func(x, y int) bool { return (x*y%2+x*y%3)%2 == 0 },
+		func(x, y int) bool { return ((x+y)%2+x*y%3)%2 == 0 },
+	}
+)
+
+// Code is an encoded QR code (modules are indexed [y][x], true is dark)
+type Code struct {
+	version  int
+	level    int
+	size     int
+	modules  [][]bool
+	function [][]bool
+}
+
+func init() {
+	x := 1
+	for i := range 255 {
+		expTable[i] = byte(x)
+		expTable[i+255] = byte(x)
+		logTable[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= gfPoly
+		}
+	}
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTable[int(logTable[a])+int(logTable[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return expTable[int(logTable[a])+255-int(logTable[b])]
+}
+
+// Version is the QR version (1-40) of the code
+func (c Code) Version() int {
+	return c.version
+}
+
+// Size is the width (and height) of the code in modules (without the quiet zone)
+func (c Code) Size() int {
+	return c.size
+}
+
+// Dark indicates if the module at x, y is dark (outside the code is light)
+func (c Code) Dark(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.size || y >= c.size {
+		return false
+	}
+	return c.modules[y][x]
+}
+
+// Terminal renders the code with unicode half blocks (two rows per line), the blocks are the
+// light modules so the code reads on a dark terminal, ansi forces white on black colors
+func (c Code) Terminal(ansi bool) string {
+	var b strings.Builder
+	start, end := -quietZone, c.size+quietZone
+	for y := start; y < end; y += 2 {
+		if ansi {
+			b.WriteString(ansiStart)
+		}
+		for x := start; x < end; x++ {
+			top, bottom := !c.Dark(x, y), !c.Dark(x, y+1) && y+1 < end
+			switch {
+			case top && bottom:
+				b.WriteRune('█')
+			case top:
+				b.WriteRune('▀')
+			case bottom:
+				b.WriteRune('▄')
+			default:
+				b.WriteRune(' ')
+			}
+		}
+		if ansi {
+			b.WriteString(ansiEnd)
+		}
+		b.WriteRune('\n')
+	}
+	return b.String()
+}
+
+// Image renders the code as a grayscale image, scale is the pixels per module
+func (c Code) Image(scale int) image.Image {
+	if scale < 1 {
+		scale = 1
+	}
+	width := (c.size + 2*quietZone) * scale
+	img := image.NewGray(image.Rect(0, 0, width, width))
+	for py := range width {
+		for px := range width {
+			shade := color.White
+			if c.Dark(px/scale-quietZone, py/scale-quietZone) {
+				shade = color.Black
+			}
+			img.Set(px, py, shade)
+		}
+	}
+	return img
+}
+
+// PNG writes the code as a png image, scale is the pixels per module
+func (c Code) PNG(w io.Writer, scale int) error {
+	if scale < 1 {
+		return errors.New("invalid png scale, must be >= 1")
+	}
+	writer := bufio.NewWriter(w)
+	if err := png.Encode(writer, c.Image(scale)); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// rawModules is the number of data (and error correction) modules for a version
+func rawModules(version int) int {
+	result := (16*version+128)*version + 64
+	if version >= 2 {
+		align := version/7 + 2
+		result -= (25*align-10)*align - 55
+		if version >= 7 {
+			result -= 36
+		}
+	}
+	return result
+}
+
+// dataCodewords is the number of data (non error correction) codewords for a version
+func dataCodewords(level, version int) int {
+	return rawModules(version)/8 - eccPerBlock[level][version]*eccBlocks[level][version]
+}
+
+// blockSizes are the data codeword lengths of each block (short blocks first)
+func blockSizes(level, version int) []int {
+	count := eccBlocks[level][version]
+	total := rawModules(version) / 8
+	short := count - total%count
+	shortLen := total/count - eccPerBlock[level][version]
+	sizes := make([]int, count)
+	for i := range sizes {
+		sizes[i] = shortLen
+		if i >= short {
+			sizes[i]++
+		}
+	}
+	return sizes
+}
+
+// alignments are the center positions (both axes) of the alignment patterns
+func alignments(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	count := version/7 + 2
+	step := (version*8 + count*3 + 5) / (count*4 - 4) * 2
+	result := make([]int, count)
+	result[0] = 6
+	pos := version*4 + 17 - 7
+	for i := count - 1; i >= 1; i-- {
+		result[i] = pos
+		pos -= step
+	}
+	return result
+}
+
+// formatBits are the (masked) format information bits for a level and mask
+func formatBits(level, mask int) int {
+	data := formatLevels[level]<<3 | mask
+	rem := data
+	for range 10 {
+		rem = (rem << 1) ^ ((rem >> 9) * formatPoly)
+	}
+	return (data<<10 | rem) ^ formatMask
+}
+
+// versionBits are the version information bits (version 7+)
+func versionBits(version int) int {
+	rem := version
+	for range 12 {
+		rem = (rem << 1) ^ ((rem >> 11) * versionPoly)
+	}
+	return version<<12 | rem
+}
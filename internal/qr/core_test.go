@@ -0,0 +1,295 @@
+package qr_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/enckse/lockbox/internal/qr"
+)
+
+func TestEncodeErrors(t *testing.T) {
+	if _, err := qr.Encode(nil); err == nil || err.Error() != "no data to encode" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := qr.Encode(bytes.Repeat([]byte("a"), 2332)); err == nil || err.Error() != "data too long for a QR code: 2332 bytes" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestEncodeVersion(t *testing.T) {
+	for _, check := range []struct {
+		length  int
+		version int
+	}{{1, 1}, {14, 1}, {15, 2}, {106, 6}, {107, 7}, {2331, 40}} {
+		c, err := qr.Encode(bytes.Repeat([]byte("a"), check.length))
+		if err != nil {
+			t.Errorf("invalid error: %v", err)
+		}
+		if c.Version() != check.version || c.Size() != check.version*4+17 {
+			t.Errorf("invalid version: %d %d (%d)", c.Version(), c.Size(), check.length)
+		}
+	}
+}
+
+func TestEncodeGolden(t *testing.T) {
+	// NOTE: the expected modules are from an independent encoder (rsc.io/qr/coding, level M)
+	// given the same version and mask
+	for _, check := range []struct {
+		data   string
+		golden string
+	}{
+		{"lockbox", `
+#######..###..#######
+#.....#..##.#.#.....#
+#.###.#.##.##.#.###.#
+#.###.#.#.#...#.###.#
+#.###.#.###.#.#.###.#
+#.....#.####..#.....#
+#######.#.#.#.#######
+........###..........
+#.#####...##..#####..
+##.###.#..######.#..#
+...##.#.##..#.##.#.#.
+...#.#.....#####.##..
+.######.....#..##..##
+........#...#...#.#.#
+#######..###.#...#.#.
+#.....#.#.......#####
+#.###.#.##.#.#..#..#.
+#.###.#.#.#######....
+#.###.#.#.#.#.#..#...
+#.....#..##########..
+#######.#...#..#...#.
+`},
+		{"otpauth://totp/lb:test?secret=JBSWY3DPEHPK3PXP", `
+#######.##..#..#.#...###..#######
+#.....#..###.....#.#....#.#.....#
+#.###.#...####.#...#.####.#.###.#
+#.###.#.#...#.##.##.##.##.#.###.#
+#.###.#.#####.#...###.#...#.###.#
+#.....#.##.#.#..#..#....#.#.....#
+#######.#.#.#.#.#.#.#.#.#.#######
+........#.#.#.####...###.........
+#...#.#####..#..####.#...#####..#
+..###....##..####......#.....##.#
+##.######..##..#.#..######.###.#.
+.##.#..#.####.##.##.###.##.#...##
+..#..##..###.##...###..#..#..#.##
+.###.#.###.###..##.#.#...###.##..
+..##..######.##.#.##...##.###.##.
+##..##...#..#....#####....###..##
+#..##.#.####.###.#..###.###.###..
+.#...#...#####.###...#.#.#.#..#.#
+.#.#..#.#....#.##.#....#..####.#.
+#..#.#..###.##..#...#.###...#...#
+.###.##.#..#.#..#..#..#...##...##
+##.....##.##.##...####.##.##...#.
+..#...#.#.#....#....##.######..#.
+........###..#.####..##..##.##..#
+##.####.....##..##.#.#########...
+........##.#.####.#....##...#.###
+#######.######.#.#...##.#.#.#..#.
+#.....#...###...####.#..#...#...#
+#.###.#.##.##.#...#.#...######.#.
+#.###.#.......#.#..#..##.####.##.
+#.###.#....####.####..#.#.#.#.#..
+#.....#..#...#...#######....#....
+#######.####...#.#...#.#####..#.#
+`},
+	} {
+		c, err := qr.Encode([]byte(check.data))
+		if err != nil {
+			t.Errorf("invalid error: %v", err)
+		}
+		var b strings.Builder
+		b.WriteString("\n")
+		for y := range c.Size() {
+			for x := range c.Size() {
+				if c.Dark(x, y) {
+					b.WriteString("#")
+				} else {
+					b.WriteString(".")
+				}
+			}
+			b.WriteString("\n")
+		}
+		if b.String() != check.golden {
+			t.Errorf("invalid modules for %s:%s", check.data, b.String())
+		}
+	}
+}
+
+func TestEncodeFinders(t *testing.T) {
+	c, _ := qr.Encode([]byte("otpauth://totp/lb:test?secret=JBSWY3DPEHPK3PXP"))
+	size := c.Size()
+	for _, corner := range [][2]int{{0, 0}, {size - 7, 0}, {0, size - 7}} {
+		for y := range 7 {
+			for x := range 7 {
+				ring := max(abs(x-3), abs(y-3))
+				if c.Dark(corner[0]+x, corner[1]+y) != (ring != 2) {
+					t.Errorf("invalid finder at: %d %d", corner[0]+x, corner[1]+y)
+				}
+			}
+		}
+	}
+	for i := 8; i < size-8; i++ {
+		if c.Dark(i, 6) != (i%2 == 0) || c.Dark(6, i) != (i%2 == 0) {
+			t.Errorf("invalid timing at: %d", i)
+		}
+	}
+	if !c.Dark(8, size-8) {
+		t.Error("dark module not set")
+	}
+	if c.Dark(-1, 0) || c.Dark(0, size) {
+		t.Error("outside the code should be light")
+	}
+}
+
+func TestTerminal(t *testing.T) {
+	c, _ := qr.Encode([]byte("abc"))
+	lines := strings.Split(strings.TrimSuffix(c.Terminal(false), "\n"), "\n")
+	if len(lines) != 15 {
+		t.Errorf("invalid lines: %d", len(lines))
+	}
+	for _, line := range lines {
+		if len([]rune(line)) != 29 {
+			t.Errorf("invalid line: %s", line)
+		}
+	}
+	if lines[0] != strings.Repeat("█", 29) || lines[14] != strings.Repeat("▀", 29) {
+		t.Error("invalid quiet zone")
+	}
+	if !strings.HasPrefix(lines[2], "████ ▄▄▄▄▄ █") {
+		t.Errorf("invalid finder: %s", lines[2])
+	}
+	ansi := c.Terminal(true)
+	if !strings.HasPrefix(ansi, "\x1b[97;40m█") || !strings.HasSuffix(ansi, "▀\x1b[0m\n") {
+		t.Errorf("invalid ansi: %q", ansi)
+	}
+}
+
+func TestPNG(t *testing.T) {
+	c, _ := qr.Encode([]byte("abc"))
+	var buf bytes.Buffer
+	if err := c.PNG(&buf, 0); err == nil || err.Error() != "invalid png scale, must be >= 1" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if err := c.PNG(&buf, 4); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Errorf("invalid png: %v", err)
+	}
+	bounds := img.Bounds()
+	if bounds.Dx() != 116 || bounds.Dy() != 116 {
+		t.Errorf("invalid size: %v", bounds)
+	}
+	for _, check := range []struct {
+		x, y int
+		dark bool
+	}{{0, 0, false}, {15, 15, false}, {16, 16, true}, {19, 19, true}, {20, 20, false}, {32, 32, true}} {
+		r, _, _, _ := img.At(check.x, check.y).RGBA()
+		if (r == 0) != check.dark {
+			t.Errorf("invalid pixel: %d %d", check.x, check.y)
+		}
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+type rotated struct {
+	image.Image
+}
+
+func (r rotated) At(x, y int) color.Color {
+	return r.Image.At(y, r.Bounds().Dx()-1-x)
+}
+
+func TestDecode(t *testing.T) {
+	for _, length := range []int{1, 50, 300, 2331} {
+		data := bytes.Repeat([]byte("otpauth://totp/lb:test?secret=JBSWY3DPEHPK3PXP&"), length/47+1)[:length]
+		c, _ := qr.Encode(data)
+		for _, scale := range []int{1, 3} {
+			got, err := qr.Decode(c.Image(scale))
+			if err != nil || !bytes.Equal(got, data) {
+				t.Errorf("invalid decode: %d %d %v", length, scale, err)
+			}
+		}
+		got, err := qr.Decode(rotated{c.Image(2)})
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("invalid rotated decode: %d %v", length, err)
+		}
+	}
+}
+
+func TestDecodeDamaged(t *testing.T) {
+	data := []byte("otpauth://totp/lb:test?secret=JBSWY3DPEHPK3PXP")
+	c, _ := qr.Encode(data)
+	img := image.NewGray(c.Image(4).Bounds())
+	draw.Draw(img, img.Bounds(), c.Image(4), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(60, 60, 72, 72), image.Black, image.Point{}, draw.Src)
+	got, err := qr.Decode(img)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("invalid decode: %v", err)
+	}
+	draw.Draw(img, image.Rect(40, 40, 120, 120), image.Black, image.Point{}, draw.Src)
+	if _, err := qr.Decode(img); err == nil || err.Error() != "too many errors to correct in QR code" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := qr.Decode(image.NewGray(image.Rect(0, 0, 100, 100))); err == nil || err.Error() != "no QR code found in image" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestDecodeInverted(t *testing.T) {
+	data := []byte("my wifi password")
+	c, _ := qr.Encode(data)
+	src := c.Image(3)
+	img := image.NewGray(src.Bounds())
+	for y := range img.Bounds().Dy() {
+		for x := range img.Bounds().Dx() {
+			r, _, _, _ := src.At(x, y).RGBA()
+			img.SetGray(x, y, color.Gray{Y: 255 - uint8(r>>8)})
+		}
+	}
+	got, err := qr.Decode(img)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("invalid decode: %v", err)
+	}
+}
+
+func TestRead(t *testing.T) {
+	data := []byte("otpauth://totp/lb:test?secret=JBSWY3DPEHPK3PXP")
+	c, _ := qr.Encode(data)
+	var buf bytes.Buffer
+	if err := c.PNG(&buf, 3); err != nil {
+		t.Fatalf("invalid error: %v", err)
+	}
+	got, err := qr.Read(&buf)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("invalid png read: %v", err)
+	}
+	buf.Reset()
+	if err := jpeg.Encode(&buf, c.Image(5), &jpeg.Options{Quality: 60}); err != nil {
+		t.Fatalf("invalid error: %v", err)
+	}
+	got, err = qr.Read(&buf)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("invalid jpeg read: %v", err)
+	}
+	if _, err := qr.Read(strings.NewReader("abc")); err == nil || err.Error() != "unable to read image: image: unknown format" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
@@ -0,0 +1,664 @@
+// Package qr handles decoding QR codes from images
+package qr
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"image"
+	// NOTE: register the supported image formats
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"math"
+	"math/bits"
+	"slices"
+	"strings"
+)
+
+const (
+	modeTerminator = 0x0
+	modeNumeric    = 0x1
+	modeAlpha      = 0x2
+	modeAppend     = 0x3
+	modeECI        = 0x7
+	modeKanji      = 0x8
+	alphaChars     = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+	// maxFinders and maxTriples limit the finder pattern candidates (and combinations) tried
+	maxFinders = 24
+	maxTriples = 4
+	// maxFormatErrors is the most bit errors a format information read can recover from
+	maxFormatErrors = 3
+)
+
+var (
+	errNoCode = errors.New("no QR code found in image")
+	// finderRatio is the dark:light:dark:light:dark (1:1:3:1:1) ratio of finder patterns
+	finderRatio = [5]float64{1, 1, 3, 1, 1}
+)
+
+type (
+	bitmap struct {
+		width  int
+		height int
+		dark   []bool
+	}
+	finder struct {
+		x, y   float64
+		module float64
+		count  int
+	}
+	bitReader struct {
+		data   []byte
+		offset int
+	}
+)
+
+// Read will decode the content of a QR code from a (png or jpeg) image
+func Read(r io.Reader) ([]byte, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read image: %w", err)
+	}
+	return Decode(img)
+}
+
+// Decode will decode the content of a QR code from an image, the code is expected to be
+// (roughly) flat, e.g. a screenshot, but can be scaled or rotated
+func Decode(img image.Image) ([]byte, error) {
+	bitmap := newBitmap(img)
+	var lastErr error
+	// NOTE: codes are also tried inverted (light on dark, e.g. a terminal rendering)
+	for _, invert := range []bool{false, true} {
+		if invert {
+			for i := range bitmap.dark {
+				bitmap.dark[i] = !bitmap.dark[i]
+			}
+		}
+		data, err := bitmap.decode()
+		if err == nil {
+			return data, nil
+		}
+		if lastErr == nil || !errors.Is(err, errNoCode) {
+			lastErr = err
+		}
+	}
+	return nil, lastErr
+}
+
+// newBitmap converts the image to dark/light pixels (via an Otsu threshold on luminance)
+func newBitmap(img image.Image) bitmap {
+	bounds := img.Bounds()
+	b := bitmap{width: bounds.Dx(), height: bounds.Dy()}
+	luma := make([]uint8, b.width*b.height)
+	var histogram [256]int
+	for y := range b.height {
+		for x := range b.width {
+			r, g, bl, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			l := uint8((299*r + 587*g + 114*bl) / 1000 >> 8)
+			luma[y*b.width+x] = l
+			histogram[l]++
+		}
+	}
+	total := len(luma)
+	var sum float64
+	for i, count := range histogram {
+		sum += float64(i * count)
+	}
+	var sumBack, best float64
+	var weightBack int
+	threshold := 127
+	for i, count := range histogram {
+		weightBack += count
+		if weightBack == 0 {
+			continue
+		}
+		weightFore := total - weightBack
+		if weightFore == 0 {
+			break
+		}
+		sumBack += float64(i * count)
+		meanBack := sumBack / float64(weightBack)
+		meanFore := (sum - sumBack) / float64(weightFore)
+		between := float64(weightBack) * float64(weightFore) * (meanBack - meanFore) * (meanBack - meanFore)
+		if between > best {
+			best = between
+			threshold = i
+		}
+	}
+	b.dark = make([]bool, total)
+	for i, l := range luma {
+		b.dark[i] = int(l) <= threshold
+	}
+	return b
+}
+
+func (b bitmap) at(x, y int) bool {
+	if x < 0 || y < 0 || x >= b.width || y >= b.height {
+		return false
+	}
+	return b.dark[y*b.width+x]
+}
+
+func (b bitmap) decode() ([]byte, error) {
+	var lastErr error
+	for _, triple := range finderTriples(b.finders()) {
+		topLeft, topRight, bottomLeft := triple[0], triple[1], triple[2]
+		module := (topLeft.module + topRight.module + bottomLeft.module) / 3
+		across := (math.Hypot(topRight.x-topLeft.x, topRight.y-topLeft.y) + math.Hypot(bottomLeft.x-topLeft.x, bottomLeft.y-topLeft.y)) / 2
+		estimate := int(math.Round((across/module + 7 - 17) / 4))
+		for _, offset := range []int{0, -1, 1, -2, 2} {
+			version := estimate + offset
+			if version < minVersion || version > maxVersion {
+				continue
+			}
+			c := b.sample(version, topLeft, topRight, bottomLeft)
+			data, err := c.read()
+			if err == nil {
+				return data, nil
+			}
+			if lastErr == nil {
+				lastErr = err
+			}
+		}
+	}
+	if lastErr == nil {
+		lastErr = errNoCode
+	}
+	return nil, lastErr
+}
+
+// finderRuns checks that run lengths match the finder ratio, returning the module size
+func finderRuns(runs [5]int) (float64, bool) {
+	total := 0
+	for _, r := range runs {
+		if r == 0 {
+			return 0, false
+		}
+		total += r
+	}
+	if total < 7 {
+		return 0, false
+	}
+	module := float64(total) / 7
+	for i, r := range runs {
+		expect := finderRatio[i] * module
+		if math.Abs(float64(r)-expect) > expect/2+0.5 {
+			return 0, false
+		}
+	}
+	return module, true
+}
+
+// crossCheck counts the finder runs along a line through x, y (the center run, dx/dy is the
+// direction), returning the center (offset along the line) and the module size
+func (b bitmap) crossCheck(x, y, dx, dy int, maxRun int) (float64, float64, bool) {
+	var runs [5]int
+	walk := func(pos, step, state, end int) (int, bool) {
+		for ; state != end; state += step {
+			for {
+				px, py := x+dx*pos, y+dy*pos
+				if px < 0 || py < 0 || px >= b.width || py >= b.height || b.at(px, py) != (state%2 == 0) {
+					break
+				}
+				runs[state]++
+				if runs[state] > maxRun {
+					return 0, false
+				}
+				pos += step
+			}
+		}
+		return pos, true
+	}
+	if _, ok := walk(0, -1, 2, -1); !ok {
+		return 0, 0, false
+	}
+	end, ok := walk(1, 1, 2, 5)
+	if !ok {
+		return 0, 0, false
+	}
+	module, ok := finderRuns(runs)
+	if !ok {
+		return 0, 0, false
+	}
+	return float64(end-runs[4]-runs[3]) - float64(runs[2])/2, module, true
+}
+
+// finders locates the (up to) 3 most likely finder patterns
+func (b bitmap) finders() []finder {
+	var found []finder
+	for y := range b.height {
+		var runs [5]int
+		state := 0
+		for x := 0; x <= b.width; x++ {
+			dark := x < b.width && b.at(x, y)
+			if state == 0 && runs[0] == 0 && !dark {
+				continue
+			}
+			if x < b.width && dark == (state%2 == 0) {
+				runs[state]++
+				continue
+			}
+			if state < 4 {
+				state++
+				runs[state] = 1
+				continue
+			}
+			if module, ok := finderRuns(runs); ok {
+				centerX := float64(x-runs[4]-runs[3]) - float64(runs[2])/2
+				found = b.addFinder(found, centerX, y, module)
+			}
+			// shift to the next (dark, light) pair of runs
+			runs = [5]int{runs[2], runs[3], runs[4], 1, 0}
+			state = 3
+		}
+	}
+	slices.SortStableFunc(found, func(a, b finder) int {
+		return b.count - a.count
+	})
+	var result []finder
+	for _, f := range found {
+		if f.count < 2 || len(result) == maxFinders {
+			break
+		}
+		result = append(result, f)
+	}
+	return result
+}
+
+// finderTriples orders the (top left, top right, bottom left) combinations of finders, best
+// first, by how well they form the right angle (with equal sides) of a QR code
+func finderTriples(finders []finder) [][3]finder {
+	type scored struct {
+		triple [3]finder
+		score  float64
+	}
+	var options []scored
+	for i := range finders {
+		for j := i + 1; j < len(finders); j++ {
+			for k := j + 1; k < len(finders); k++ {
+				topLeft, topRight, bottomLeft := orderFinders(finders[i], finders[j], finders[k])
+				ax, ay := topRight.x-topLeft.x, topRight.y-topLeft.y
+				bx, by := bottomLeft.x-topLeft.x, bottomLeft.y-topLeft.y
+				a, b := math.Hypot(ax, ay), math.Hypot(bx, by)
+				module := (topLeft.module + topRight.module + bottomLeft.module) / 3
+				if a < 7*module || b < 7*module {
+					continue
+				}
+				spread := math.Max(topLeft.module, math.Max(topRight.module, bottomLeft.module)) - math.Min(topLeft.module, math.Min(topRight.module, bottomLeft.module))
+				score := math.Abs(a-b)/math.Max(a, b) + math.Abs(ax*bx+ay*by)/(a*b) + spread/module
+				options = append(options, scored{[3]finder{topLeft, topRight, bottomLeft}, score})
+			}
+		}
+	}
+	slices.SortStableFunc(options, func(a, b scored) int {
+		return cmp.Compare(a.score, b.score)
+	})
+	var result [][3]finder
+	for _, o := range options {
+		if len(result) == maxTriples {
+			break
+		}
+		result = append(result, o.triple)
+	}
+	return result
+}
+
+func (b bitmap) addFinder(found []finder, centerX float64, y int, module float64) []finder {
+	maxRun := int(module*4) + 2
+	cx := int(centerX)
+	centerY, vertical, ok := b.crossCheck(cx, y, 0, 1, maxRun)
+	if !ok {
+		return found
+	}
+	centerY += float64(y)
+	refined, horizontal, ok := b.crossCheck(cx, int(centerY), 1, 0, maxRun)
+	if !ok {
+		return found
+	}
+	centerX = refined + float64(cx)
+	module = (module + vertical + horizontal) / 3
+	for i, f := range found {
+		if math.Abs(f.x-centerX) <= module && math.Abs(f.y-centerY) <= module && math.Abs(f.module-module) <= math.Max(1, module/2) {
+			n := float64(f.count)
+			found[i] = finder{
+				x:      (f.x*n + centerX) / (n + 1),
+				y:      (f.y*n + centerY) / (n + 1),
+				module: (f.module*n + module) / (n + 1),
+				count:  f.count + 1,
+			}
+			return found
+		}
+	}
+	return append(found, finder{x: centerX, y: centerY, module: module, count: 1})
+}
+
+// orderFinders determines the top left (the right angle), top right, and bottom left finders
+func orderFinders(a, b, c finder) (finder, finder, finder) {
+	dist := func(p, q finder) float64 {
+		return math.Hypot(p.x-q.x, p.y-q.y)
+	}
+	ab, ac, bc := dist(a, b), dist(a, c), dist(b, c)
+	topLeft, other1, other2 := a, b, c
+	switch {
+	case ab >= ac && ab >= bc:
+		topLeft, other1, other2 = c, a, b
+	case ac >= ab && ac >= bc:
+		topLeft, other1, other2 = b, a, c
+	}
+	cross := (other1.x-topLeft.x)*(other2.y-topLeft.y) - (other1.y-topLeft.y)*(other2.x-topLeft.x)
+	if cross < 0 {
+		other1, other2 = other2, other1
+	}
+	return topLeft, other1, other2
+}
+
+// sample reads the modules of a version sized code (an affine mapping from the finder centers)
+func (b bitmap) sample(version int, topLeft, topRight, bottomLeft finder) Code {
+	c := newCode(version)
+	span := float64(c.size - 7)
+	for v := range c.size {
+		for u := range c.size {
+			fu, fv := (float64(u)+0.5-3.5)/span, (float64(v)+0.5-3.5)/span
+			x := topLeft.x + fu*(topRight.x-topLeft.x) + fv*(bottomLeft.x-topLeft.x)
+			y := topLeft.y + fu*(topRight.y-topLeft.y) + fv*(bottomLeft.y-topLeft.y)
+			c.modules[v][u] = b.at(int(math.Floor(x)), int(math.Floor(y)))
+		}
+	}
+	return c
+}
+
+// format reads the level and mask from the (best of the two copies of the) format information
+func (c Code) format() (int, int, error) {
+	var first, second int
+	for i := range 15 {
+		var x1, y1, x2, y2 int
+		switch {
+		case i < 6:
+			x1, y1 = 8, i
+		case i < 8:
+			x1, y1 = 8, i+1
+		case i == 8:
+			x1, y1 = 7, 8
+		default:
+			x1, y1 = 14-i, 8
+		}
+		if i < 8 {
+			x2, y2 = c.size-1-i, 8
+		} else {
+			x2, y2 = 8, c.size-15+i
+		}
+		if c.modules[y1][x1] {
+			first |= 1 << i
+		}
+		if c.modules[y2][x2] {
+			second |= 1 << i
+		}
+	}
+	bestLevel, bestMask, bestDistance := 0, 0, maxFormatErrors+1
+	for level := range formatLevels {
+		for mask := range masks {
+			expect := formatBits(level, mask)
+			for _, read := range []int{first, second} {
+				if d := bits.OnesCount(uint(expect ^ read)); d < bestDistance {
+					bestLevel, bestMask, bestDistance = level, mask, d
+				}
+			}
+		}
+	}
+	if bestDistance > maxFormatErrors {
+		return 0, 0, errors.New("unable to read QR format information")
+	}
+	return bestLevel, bestMask, nil
+}
+
+// read extracts the content of sampled modules
+func (c Code) read() ([]byte, error) {
+	level, mask, err := c.format()
+	if err != nil {
+		return nil, err
+	}
+	c.drawFunctions()
+	c.applyMask(mask)
+	raw := make([]byte, rawModules(c.version)/8)
+	i := 0
+	c.eachData(func(x, y int) {
+		if i < len(raw)*8 && c.modules[y][x] {
+			raw[i>>3] |= 1 << (7 - i&7)
+		}
+		i++
+	})
+	sizes := blockSizes(level, c.version)
+	ecc := eccPerBlock[level][c.version]
+	blocks := make([][]byte, len(sizes))
+	offset := 0
+	for i := range sizes[len(sizes)-1] {
+		for b, size := range sizes {
+			if i < size {
+				blocks[b] = append(blocks[b], raw[offset])
+				offset++
+			}
+		}
+	}
+	for range ecc {
+		for b := range sizes {
+			blocks[b] = append(blocks[b], raw[offset])
+			offset++
+		}
+	}
+	var data []byte
+	for b, block := range blocks {
+		if err := rsCorrect(block, ecc); err != nil {
+			return nil, err
+		}
+		data = append(data, block[:sizes[b]]...)
+	}
+	return parseSegments(data, c.version)
+}
+
+func evalPoly(poly []byte, x byte) byte {
+	var result byte
+	for i := len(poly) - 1; i >= 0; i-- {
+		result = gfMul(result, x) ^ poly[i]
+	}
+	return result
+}
+
+// rsCorrect corrects (in place) errors in a block (data then ecc codewords), polynomials
+// are stored lowest degree first
+func rsCorrect(block []byte, ecc int) error {
+	syndromes := make([]byte, ecc)
+	clean := true
+	for j := range ecc {
+		var s byte
+		for _, b := range block {
+			s = gfMul(s, expTable[j]) ^ b
+		}
+		syndromes[j] = s
+		if s != 0 {
+			clean = false
+		}
+	}
+	if clean {
+		return nil
+	}
+	errTooMany := errors.New("too many errors to correct in QR code")
+	// Berlekamp-Massey for the error locator
+	locator, previous := []byte{1}, []byte{1}
+	length, shift, last := 0, 1, byte(1)
+	for k := range ecc {
+		d := syndromes[k]
+		for i := 1; i <= length && i < len(locator); i++ {
+			d ^= gfMul(locator[i], syndromes[k-i])
+		}
+		if d == 0 {
+			shift++
+			continue
+		}
+		coef := gfDiv(d, last)
+		next := make([]byte, max(len(locator), len(previous)+shift))
+		copy(next, locator)
+		for i, p := range previous {
+			next[i+shift] ^= gfMul(coef, p)
+		}
+		if 2*length <= k {
+			previous = locator
+			length = k + 1 - length
+			last = d
+			shift = 1
+		} else {
+			shift++
+		}
+		locator = next
+	}
+	if 2*length > ecc {
+		return errTooMany
+	}
+	// Chien search, an error at index i (from the end) has a locator root of a^-i
+	var positions []int
+	for i := range len(block) {
+		if evalPoly(locator, expTable[(255-i%255)%255]) == 0 {
+			positions = append(positions, i)
+		}
+	}
+	if len(positions) != length {
+		return errTooMany
+	}
+	// Forney for the error magnitudes
+	evaluator := make([]byte, ecc)
+	for i := range ecc {
+		for j := 0; j <= i && j < len(locator); j++ {
+			evaluator[i] ^= gfMul(locator[j], syndromes[i-j])
+		}
+	}
+	derivative := make([]byte, len(locator))
+	for i := 1; i < len(locator); i += 2 {
+		derivative[i-1] = locator[i]
+	}
+	for _, i := range positions {
+		inverse := expTable[(255-i%255)%255]
+		denominator := evalPoly(derivative, inverse)
+		if denominator == 0 {
+			return errTooMany
+		}
+		block[len(block)-1-i] ^= gfMul(expTable[i%255], gfDiv(evalPoly(evaluator, inverse), denominator))
+	}
+	return nil
+}
+
+func (r *bitReader) read(n int) (int, error) {
+	if r.offset+n > len(r.data)*8 {
+		return 0, errors.New("invalid QR data, not enough bits")
+	}
+	value := 0
+	for range n {
+		value = value<<1 | int(r.data[r.offset>>3]>>(7-r.offset&7)&1)
+		r.offset++
+	}
+	return value, nil
+}
+
+func (r *bitReader) remaining() int {
+	return len(r.data)*8 - r.offset
+}
+
+// parseSegments reads the (numeric, alphanumeric, and byte) segments of the data codewords
+func parseSegments(data []byte, version int) ([]byte, error) {
+	group := 0
+	if version >= 27 {
+		group = 2
+	} else if version >= 10 {
+		group = 1
+	}
+	counts := map[int][3]int{
+		modeNumeric: {10, 12, 14},
+		modeAlpha:   {9, 11, 13},
+		modeByte:    {8, 16, 16},
+		modeKanji:   {8, 10, 12},
+	}
+	r := &bitReader{data: data}
+	var result []byte
+	for r.remaining() >= 4 {
+		mode, err := r.read(4)
+		if err != nil {
+			return nil, err
+		}
+		switch mode {
+		case modeTerminator:
+			return result, nil
+		case modeAppend:
+			if _, err := r.read(16); err != nil {
+				return nil, err
+			}
+			continue
+		case modeECI:
+			// NOTE: the designator is 1, 2, or 3 bytes (by its leading bits), content is kept as is
+			first, err := r.read(8)
+			if err != nil {
+				return nil, err
+			}
+			extra := 0
+			if first&0x80 != 0 {
+				extra = 1
+				if first&0x40 != 0 {
+					extra = 2
+				}
+			}
+			if _, err := r.read(extra * 8); err != nil {
+				return nil, err
+			}
+			continue
+		case modeKanji:
+			return nil, errors.New("unsupported QR data, kanji mode")
+		}
+		sizes, ok := counts[mode]
+		if !ok {
+			return nil, fmt.Errorf("invalid QR data, unknown mode: %d", mode)
+		}
+		count, err := r.read(sizes[group])
+		if err != nil {
+			return nil, err
+		}
+		switch mode {
+		case modeByte:
+			for range count {
+				b, err := r.read(8)
+				if err != nil {
+					return nil, err
+				}
+				result = append(result, byte(b))
+			}
+		case modeNumeric:
+			for count > 0 {
+				digits := min(count, 3)
+				value, err := r.read(digits*3 + 1)
+				if err != nil {
+					return nil, err
+				}
+				result = fmt.Appendf(result, "%0*d", digits, value)
+				count -= digits
+			}
+		case modeAlpha:
+			var b strings.Builder
+			for count > 0 {
+				if count == 1 {
+					value, err := r.read(6)
+					if err != nil || value >= len(alphaChars) {
+						return nil, errors.New("invalid QR data, alphanumeric")
+					}
+					b.WriteByte(alphaChars[value])
+					break
+				}
+				value, err := r.read(11)
+				if err != nil || value/45 >= len(alphaChars) {
+					return nil, errors.New("invalid QR data, alphanumeric")
+				}
+				b.WriteByte(alphaChars[value/45])
+				b.WriteByte(alphaChars[value%45])
+				count -= 2
+			}
+			result = append(result, b.String()...)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,332 @@
+// Package qr handles encoding data into a QR code (byte mode, error correction level M)
+package qr
+
+import (
+	"errors"
+	"fmt"
+)
+
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		*b = append(*b, (value>>i)&1 != 0)
+	}
+}
+
+// Encode will encode the data (as bytes) into the smallest QR code that fits
+func Encode(data []byte) (Code, error) {
+	return encode(data, levelM)
+}
+
+func encode(data []byte, level int) (Code, error) {
+	if len(data) == 0 {
+		return Code{}, errors.New("no data to encode")
+	}
+	version := 0
+	for v := minVersion; v <= maxVersion; v++ {
+		if 4+countBits(v)+len(data)*8 <= dataCodewords(level, v)*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return Code{}, fmt.Errorf("data too long for a QR code: %d bytes", len(data))
+	}
+	codewords := addECC(dataBits(level, version, data), level, version)
+	c := newCode(version)
+	c.level = level
+	c.drawFunctions()
+	c.drawCodewords(codewords)
+	best, penalty := 0, -1
+	for mask := range masks {
+		c.applyMask(mask)
+		c.drawFormat(mask)
+		if p := c.penalty(); penalty < 0 || p < penalty {
+			best, penalty = mask, p
+		}
+		c.applyMask(mask)
+	}
+	c.applyMask(best)
+	c.drawFormat(best)
+	return c, nil
+}
+
+func countBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+func dataBits(level, version int, data []byte) []byte {
+	var bits bitBuffer
+	bits.append(modeByte, 4)
+	bits.append(len(data), countBits(version))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	capacity := dataCodewords(level, version) * 8
+	bits.append(0, min(4, capacity-len(bits)))
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := padByteFirst; len(bits) < capacity; pad ^= padByteFirst ^ padByteSecond {
+		bits.append(pad, 8)
+	}
+	result := make([]byte, len(bits)/8)
+	for i, bit := range bits {
+		if bit {
+			result[i>>3] |= 1 << (7 - i&7)
+		}
+	}
+	return result
+}
+
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for range degree {
+		for j := range result {
+			result[j] = gfMul(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 2)
+	}
+	return result
+}
+
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coef := range divisor {
+			result[i] ^= gfMul(coef, factor)
+		}
+	}
+	return result
+}
+
+// addECC splits the data into blocks, adds error correction, and interleaves the result
+func addECC(data []byte, level, version int) []byte {
+	divisor := rsDivisor(eccPerBlock[level][version])
+	sizes := blockSizes(level, version)
+	var blocks, eccs [][]byte
+	offset := 0
+	for _, size := range sizes {
+		block := data[offset : offset+size]
+		offset += size
+		blocks = append(blocks, block)
+		eccs = append(eccs, rsRemainder(block, divisor))
+	}
+	var result []byte
+	for i := range sizes[len(sizes)-1] {
+		for _, block := range blocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := range eccPerBlock[level][version] {
+		for _, ecc := range eccs {
+			result = append(result, ecc[i])
+		}
+	}
+	return result
+}
+
+func newCode(version int) Code {
+	size := version*4 + 17
+	c := Code{version: version, size: size, level: levelM}
+	c.modules = make([][]bool, size)
+	c.function = make([][]bool, size)
+	for i := range size {
+		c.modules[i] = make([]bool, size)
+		c.function[i] = make([]bool, size)
+	}
+	return c
+}
+
+func (c Code) set(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.function[y][x] = true
+}
+
+func (c Code) drawFunctions() {
+	for i := range c.size {
+		c.set(6, i, i%2 == 0)
+		c.set(i, 6, i%2 == 0)
+	}
+	for _, corner := range [][2]int{{3, 3}, {c.size - 4, 3}, {3, c.size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := corner[0]+dx, corner[1]+dy
+				if x < 0 || y < 0 || x >= c.size || y >= c.size {
+					continue
+				}
+				dist := max(abs(dx), abs(dy))
+				c.set(x, y, dist != 2 && dist != 4)
+			}
+		}
+	}
+	positions := alignments(c.version)
+	last := len(positions) - 1
+	for i, ay := range positions {
+		for j, ax := range positions {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					c.set(ax+dx, ay+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+	// NOTE: reserve the format areas, drawn (per mask) later
+	c.drawFormat(0)
+	if c.version >= 7 {
+		bits := versionBits(c.version)
+		for i := range 18 {
+			dark := (bits>>i)&1 != 0
+			a, b := c.size-11+i%3, i/3
+			c.set(a, b, dark)
+			c.set(b, a, dark)
+		}
+	}
+}
+
+func (c Code) drawFormat(mask int) {
+	bits := formatBits(c.level, mask)
+	bit := func(i int) bool {
+		return (bits>>i)&1 != 0
+	}
+	for i := range 6 {
+		c.set(8, i, bit(i))
+	}
+	c.set(8, 7, bit(6))
+	c.set(8, 8, bit(7))
+	c.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.set(14-i, 8, bit(i))
+	}
+	for i := range 8 {
+		c.set(c.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.set(8, c.size-15+i, bit(i))
+	}
+	c.set(8, c.size-8, true)
+}
+
+// eachData calls the callback for each data module in placement order
+func (c Code) eachData(cb func(x, y int)) {
+	for right := c.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := range c.size {
+			y := vert
+			if upward {
+				y = c.size - 1 - vert
+			}
+			for j := range 2 {
+				x := right - j
+				if !c.function[y][x] {
+					cb(x, y)
+				}
+			}
+		}
+	}
+}
+
+func (c Code) drawCodewords(data []byte) {
+	i := 0
+	c.eachData(func(x, y int) {
+		if i < len(data)*8 {
+			c.modules[y][x] = (data[i>>3]>>(7-i&7))&1 != 0
+		}
+		i++
+	})
+}
+
+func (c Code) applyMask(mask int) {
+	fxn := masks[mask]
+	for y := range c.size {
+		for x := range c.size {
+			if !c.function[y][x] && fxn(x, y) {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores the code (lower is better) to pick the mask that is easiest to scan
+func (c Code) penalty() int {
+	result := 0
+	finder := [][]bool{
+		{true, false, true, true, true, false, true, false, false, false, false},
+		{false, false, false, false, true, false, true, true, true, false, true},
+	}
+	dark := 0
+	for i := range c.size {
+		for _, horizontal := range []bool{true, false} {
+			get := func(j int) bool {
+				if horizontal {
+					return c.modules[i][j]
+				}
+				return c.modules[j][i]
+			}
+			run := 1
+			for j := 1; j <= c.size; j++ {
+				if j < c.size && get(j) == get(j-1) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					result += run - 2
+				}
+				run = 1
+			}
+			for j := 0; j+len(finder[0]) <= c.size; j++ {
+				for _, pattern := range finder {
+					match := true
+					for k, want := range pattern {
+						if get(j+k) != want {
+							match = false
+							break
+						}
+					}
+					if match {
+						result += 40
+					}
+				}
+			}
+		}
+		for j := range c.size {
+			if c.modules[i][j] {
+				dark++
+			}
+			if i+1 < c.size && j+1 < c.size {
+				v := c.modules[i][j]
+				if v == c.modules[i][j+1] && v == c.modules[i+1][j] && v == c.modules[i+1][j+1] {
+					result += 3
+				}
+			}
+		}
+	}
+	total := c.size * c.size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	return result + k*10
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,188 @@
+// Package shamir handles splitting/combining secrets via shamir secret sharing (GF(256))
+package shamir
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strconv"
+	"strings"
+)
+
+const (
+	prefix       = "lbs"
+	separator    = "-"
+	checksumSize = 4
+	maxShares    = 255
+)
+
+var (
+	expTable [512]byte
+	logTable [256]byte
+)
+
+// Share is a single (printable) share of a secret
+type Share struct {
+	Threshold int
+	Index     int
+	Data      []byte
+}
+
+func init() {
+	x := byte(1)
+	for i := range 255 {
+		expTable[i] = x
+		expTable[i+255] = x
+		logTable[x] = byte(i)
+		x = mul2(x) ^ x
+	}
+}
+
+func mul2(b byte) byte {
+	if b&0x80 != 0 {
+		return (b << 1) ^ 0x1b
+	}
+	return b << 1
+}
+
+func mul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTable[int(logTable[a])+int(logTable[b])]
+}
+
+func div(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return expTable[int(logTable[a])+255-int(logTable[b])]
+}
+
+func checksum(secret []byte) []byte {
+	sum := sha256.Sum256(secret)
+	return sum[:checksumSize]
+}
+
+// Split will split a secret into n shares, any k of which reconstruct the secret
+func Split(secret []byte, n, k int) ([]Share, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("no secret to split")
+	}
+	if k < 2 {
+		return nil, errors.New("threshold must be >= 2")
+	}
+	if n < k {
+		return nil, errors.New("shares must be >= threshold")
+	}
+	if n > maxShares {
+		return nil, fmt.Errorf("shares must be <= %d", maxShares)
+	}
+	data := append(bytes.Clone(secret), checksum(secret)...)
+	shares := make([]Share, n)
+	for i := range shares {
+		shares[i] = Share{Threshold: k, Index: i + 1, Data: make([]byte, len(data))}
+	}
+	coefficients := make([]byte, k)
+	for idx, b := range data {
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, err
+		}
+		coefficients[0] = b
+		for i := range shares {
+			x := byte(shares[i].Index)
+			var y byte
+			for c := k - 1; c >= 0; c-- {
+				y = mul(y, x) ^ coefficients[c]
+			}
+			shares[i].Data[idx] = y
+		}
+	}
+	clear(coefficients)
+	clear(data)
+	return shares, nil
+}
+
+// Combine will reconstruct a secret from (at least threshold) shares
+func Combine(shares []Share) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, errors.New("no shards given")
+	}
+	threshold := shares[0].Threshold
+	size := len(shares[0].Data)
+	seen := make(map[int]struct{})
+	for _, s := range shares {
+		if s.Threshold != threshold || len(s.Data) != size {
+			return nil, errors.New("shards are not from the same split")
+		}
+		if _, ok := seen[s.Index]; ok {
+			return nil, fmt.Errorf("duplicate shard: %d", s.Index)
+		}
+		seen[s.Index] = struct{}{}
+	}
+	if len(shares) < threshold {
+		return nil, fmt.Errorf("%d shards required, have %d", threshold, len(shares))
+	}
+	use := shares[:threshold]
+	data := make([]byte, size)
+	for idx := range data {
+		var value byte
+		for i, s := range use {
+			xi := byte(s.Index)
+			basis := byte(1)
+			for j, o := range use {
+				if i == j {
+					continue
+				}
+				xj := byte(o.Index)
+				basis = mul(basis, div(xj, xj^xi))
+			}
+			value ^= mul(s.Data[idx], basis)
+		}
+		data[idx] = value
+	}
+	if size <= checksumSize {
+		return nil, errors.New("shards do not reconstruct the secret")
+	}
+	secret := data[:size-checksumSize]
+	if !bytes.Equal(checksum(secret), data[size-checksumSize:]) {
+		clear(data)
+		return nil, errors.New("shards do not reconstruct the secret")
+	}
+	return secret, nil
+}
+
+// String will get the printable form of the share
+func (s Share) String() string {
+	body := strings.Join([]string{prefix, strconv.Itoa(s.Threshold), strconv.Itoa(s.Index), hex.EncodeToString(s.Data)}, separator)
+	return fmt.Sprintf("%s%s%08x", body, separator, crc32.ChecksumIEEE([]byte(body)))
+}
+
+// Parse will parse a printable share
+func Parse(text string) (Share, error) {
+	parts := strings.Split(strings.TrimSpace(text), separator)
+	if len(parts) != 5 || parts[0] != prefix {
+		return Share{}, errors.New("invalid shard format")
+	}
+	body := strings.Join(parts[:4], separator)
+	if parts[4] != fmt.Sprintf("%08x", crc32.ChecksumIEEE([]byte(body))) {
+		return Share{}, errors.New("invalid shard checksum")
+	}
+	threshold, err := strconv.Atoi(parts[1])
+	if err != nil || threshold < 2 {
+		return Share{}, errors.New("invalid shard threshold")
+	}
+	index, err := strconv.Atoi(parts[2])
+	if err != nil || index < 1 || index > maxShares {
+		return Share{}, errors.New("invalid shard index")
+	}
+	data, err := hex.DecodeString(parts[3])
+	if err != nil || len(data) == 0 {
+		return Share{}, errors.New("invalid shard data")
+	}
+	return Share{Threshold: threshold, Index: index, Data: data}, nil
+}
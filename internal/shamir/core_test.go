@@ -0,0 +1,76 @@
+package shamir_test
+
+import (
+	"testing"
+
+	"github.com/enckse/lockbox/internal/shamir"
+)
+
+func TestSplitErrors(t *testing.T) {
+	if _, err := shamir.Split(nil, 5, 3); err == nil || err.Error() != "no secret to split" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := shamir.Split([]byte("a"), 5, 1); err == nil || err.Error() != "threshold must be >= 2" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := shamir.Split([]byte("a"), 2, 3); err == nil || err.Error() != "shares must be >= threshold" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := shamir.Split([]byte("a"), 256, 3); err == nil || err.Error() != "shares must be <= 255" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestSplitCombine(t *testing.T) {
+	shares, err := shamir.Split([]byte("my secret password"), 5, 3)
+	if err != nil {
+		t.Fatalf("invalid error: %v", err)
+	}
+	if len(shares) != 5 {
+		t.Errorf("invalid shares: %d", len(shares))
+	}
+	for _, set := range [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}, {0, 1, 2, 3, 4}} {
+		var use []shamir.Share
+		for _, idx := range set {
+			use = append(use, shares[idx])
+		}
+		b, err := shamir.Combine(use)
+		if err != nil || string(b) != "my secret password" {
+			t.Errorf("invalid combine: %s %v", string(b), err)
+		}
+	}
+	if _, err := shamir.Combine(shares[0:2]); err == nil || err.Error() != "3 shards required, have 2" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := shamir.Combine([]shamir.Share{shares[0], shares[0], shares[1]}); err == nil || err.Error() != "duplicate shard: 1" {
+		t.Errorf("invalid error: %v", err)
+	}
+	other, _ := shamir.Split([]byte("my secret password"), 5, 3)
+	if _, err := shamir.Combine([]shamir.Share{shares[0], shares[1], other[2]}); err == nil || err.Error() != "shards do not reconstruct the secret" {
+		t.Errorf("invalid error: %v", err)
+	}
+	if _, err := shamir.Combine(nil); err == nil || err.Error() != "no shards given" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	shares, _ := shamir.Split([]byte("abc"), 3, 2)
+	text := shares[1].String()
+	s, err := shamir.Parse(text)
+	if err != nil || s.Index != 2 || s.Threshold != 2 || string(s.Data) != string(shares[1].Data) {
+		t.Errorf("invalid parse: %v %v", s, err)
+	}
+	if _, err := shamir.Parse("xyz"); err == nil || err.Error() != "invalid shard format" {
+		t.Errorf("invalid error: %v", err)
+	}
+	bad := []byte(text)
+	if bad[10] == 'a' {
+		bad[10] = 'b'
+	} else {
+		bad[10] = 'a'
+	}
+	if _, err := shamir.Parse(string(bad)); err == nil || err.Error() != "invalid shard checksum" {
+		t.Errorf("invalid error: %v", err)
+	}
+}
@@ -0,0 +1,288 @@
+// Package strength handles estimating password strength (zxcvbn-style minimum entropy matching)
+package strength
+
+import (
+	_ "embed"
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	// DictionaryPattern is a common password/word
+	DictionaryPattern = "dictionary"
+	// UserInputPattern is a user supplied (e.g. entry title) value
+	UserInputPattern = "user input"
+	// SequencePattern is a run of sequential characters (e.g. abc, 123)
+	SequencePattern = "sequence"
+	// RepeatPattern is a run of repeated characters (e.g. aaa)
+	RepeatPattern = "repeat"
+	// KeyboardPattern is a run of adjacent keyboard keys (e.g. qwerty)
+	KeyboardPattern = "keyboard"
+	// DatePattern is a (4 digit) year
+	DatePattern = "date"
+	minMatch    = 3
+	minKeyboard = 4
+	minYear     = 1900
+	maxYear     = 2039
+)
+
+type (
+	// Match is a pattern found within a password
+	Match struct {
+		Pattern string
+		Token   string
+		start   int
+		end     int
+		entropy float64
+	}
+	// Result is the estimated strength of a password
+	Result struct {
+		Entropy float64
+		Matches []Match
+	}
+)
+
+var (
+	//go:embed common.txt
+	commonData string
+	common     = loadRanked(commonData)
+	keyboard   = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm", "1qaz2wsx3edc4rfv5tgb6yhn7ujm8ik9ol0p"}
+	leet       = map[rune]rune{'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g', '1': 'i', '!': 'i', '|': 'l', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't', '2': 'z'}
+)
+
+func loadRanked(data string) map[string]int {
+	ranked := make(map[string]int)
+	for line := range strings.SplitSeq(data, "\n") {
+		word := strings.TrimSpace(line)
+		if word == "" {
+			continue
+		}
+		if _, ok := ranked[word]; !ok {
+			ranked[word] = len(ranked) + 1
+		}
+	}
+	return ranked
+}
+
+// Estimate will estimate the entropy (bits) of a password, user inputs (e.g. title) are treated as known words
+func Estimate(password string, inputs ...string) Result {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return Result{}
+	}
+	perChar := math.Log2(float64(poolSize(runes)))
+	matches := findMatches(runes, inputs)
+	size := len(runes)
+	best := make([]float64, size+1)
+	chosen := make([]*Match, size+1)
+	for i := 1; i <= size; i++ {
+		best[i] = best[i-1] + perChar
+		chosen[i] = nil
+		for idx := range matches {
+			m := &matches[idx]
+			if m.end != i {
+				continue
+			}
+			if e := best[m.start] + m.entropy; e < best[i] {
+				best[i] = e
+				chosen[i] = m
+			}
+		}
+	}
+	var used []Match
+	for i := size; i > 0; {
+		m := chosen[i]
+		if m == nil {
+			i--
+			continue
+		}
+		used = append([]Match{*m}, used...)
+		i = m.start
+	}
+	return Result{Entropy: best[size], Matches: used}
+}
+
+func poolSize(runes []rune) int {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII && unicode.IsPrint(r):
+			symbol = true
+		default:
+			other = true
+		}
+	}
+	pool := 0
+	for _, set := range []struct {
+		has  bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if set.has {
+			pool += set.size
+		}
+	}
+	return pool
+}
+
+func findMatches(runes []rune, inputs []string) []Match {
+	var matches []Match
+	lower := []rune(strings.ToLower(string(runes)))
+	size := len(lower)
+	unleet := make([]rune, size)
+	for i, r := range lower {
+		if sub, ok := leet[r]; ok {
+			unleet[i] = sub
+		} else {
+			unleet[i] = r
+		}
+	}
+	user := make(map[string]int)
+	for _, input := range inputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		if len([]rune(input)) >= minMatch {
+			user[input] = 1
+		}
+	}
+	for i := 0; i < size; i++ {
+		for j := i + minMatch; j <= size; j++ {
+			for _, candidate := range []struct {
+				ranked  map[string]int
+				pattern string
+			}{{user, UserInputPattern}, {common, DictionaryPattern}} {
+				word := string(lower[i:j])
+				substitutions := 0
+				rank, ok := candidate.ranked[word]
+				if !ok {
+					word = string(unleet[i:j])
+					rank, ok = candidate.ranked[word]
+					for k := i; k < j; k++ {
+						if lower[k] != unleet[k] {
+							substitutions++
+						}
+					}
+				}
+				if !ok {
+					continue
+				}
+				entropy := math.Log2(float64(rank)) + upperEntropy(runes[i:j]) + float64(substitutions)
+				matches = append(matches, Match{Pattern: candidate.pattern, Token: string(runes[i:j]), start: i, end: j, entropy: entropy})
+			}
+		}
+	}
+	matches = append(matches, runMatches(runes, lower)...)
+	matches = append(matches, keyboardMatches(runes, lower)...)
+	for i := 0; i+4 <= size; i++ {
+		token := string(runes[i : i+4])
+		year := 0
+		valid := true
+		for _, r := range token {
+			if r < '0' || r > '9' {
+				valid = false
+				break
+			}
+			year = year*10 + int(r-'0')
+		}
+		if valid && year >= minYear && year <= maxYear {
+			matches = append(matches, Match{Pattern: DatePattern, Token: token, start: i, end: i + 4, entropy: math.Log2(maxYear - minYear + 1)})
+		}
+	}
+	return matches
+}
+
+func upperEntropy(runes []rune) float64 {
+	upper := 0
+	for _, r := range runes {
+		if unicode.IsUpper(r) {
+			upper++
+		}
+	}
+	switch {
+	case upper == 0:
+		return 0
+	case upper == 1 && unicode.IsUpper(runes[0]):
+		return 1
+	case upper == len(runes):
+		return 1
+	}
+	return float64(upper)
+}
+
+func runMatches(runes, lower []rune) []Match {
+	var matches []Match
+	size := len(lower)
+	for i := 0; i < size; {
+		j := i + 1
+		for j < size && lower[j] == lower[i] {
+			j++
+		}
+		if j-i >= minMatch {
+			entropy := math.Log2(float64(poolSize(runes[i:i+1]))) + math.Log2(float64(j-i))
+			matches = append(matches, Match{Pattern: RepeatPattern, Token: string(runes[i:j]), start: i, end: j, entropy: entropy})
+		}
+		i = j
+	}
+	for i := 0; i+1 < size; {
+		delta := lower[i+1] - lower[i]
+		j := i + 1
+		if delta == 1 || delta == -1 {
+			for j+1 < size && lower[j+1]-lower[j] == delta && sameClass(lower[i], lower[j+1]) {
+				j++
+			}
+		}
+		if j-i+1 >= minMatch && sameClass(lower[i], lower[j]) {
+			entropy := math.Log2(float64(poolSize(runes[i:i+1]))) + math.Log2(float64(j-i+1))
+			if delta < 0 {
+				entropy++
+			}
+			matches = append(matches, Match{Pattern: SequencePattern, Token: string(runes[i : j+1]), start: i, end: j + 1, entropy: entropy})
+			i = j
+			continue
+		}
+		i++
+	}
+	return matches
+}
+
+func sameClass(a, b rune) bool {
+	isAlpha := func(r rune) bool { return r >= 'a' && r <= 'z' }
+	isDigit := func(r rune) bool { return r >= '0' && r <= '9' }
+	return (isAlpha(a) && isAlpha(b)) || (isDigit(a) && isDigit(b))
+}
+
+func keyboardMatches(runes, lower []rune) []Match {
+	var matches []Match
+	size := len(lower)
+	for i := 0; i < size; i++ {
+		for j := size; j >= i+minKeyboard; j-- {
+			token := string(lower[i:j])
+			found := false
+			for _, row := range keyboard {
+				if strings.Contains(row, token) || strings.Contains(reverse(row), token) {
+					found = true
+					break
+				}
+			}
+			if found {
+				entropy := math.Log2(float64(len(keyboard)*2)) + math.Log2(float64(j-i)) + upperEntropy(runes[i:j])
+				matches = append(matches, Match{Pattern: KeyboardPattern, Token: string(runes[i:j]), start: i, end: j, entropy: entropy})
+				break
+			}
+		}
+	}
+	return matches
+}
+
+func reverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
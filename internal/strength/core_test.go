@@ -0,0 +1,50 @@
+package strength_test
+
+import (
+	"testing"
+
+	"github.com/enckse/lockbox/internal/strength"
+)
+
+func patterns(r strength.Result) []string {
+	var p []string
+	for _, m := range r.Matches {
+		p = append(p, m.Pattern)
+	}
+	return p
+}
+
+func TestEstimate(t *testing.T) {
+	if r := strength.Estimate(""); r.Entropy != 0 || len(r.Matches) != 0 {
+		t.Errorf("invalid result: %v", r)
+	}
+	for password, expect := range map[string]string{
+		"password":    strength.DictionaryPattern,
+		"P@ssw0rd":    strength.DictionaryPattern,
+		"password123": strength.DictionaryPattern,
+		"qwerty2019":  strength.DictionaryPattern,
+		"aaaaaaaa":    strength.RepeatPattern,
+		"zyxwvu":      strength.SequencePattern,
+		"asdfghjk":    strength.KeyboardPattern,
+		"lockboxtest": strength.UserInputPattern,
+	} {
+		r := strength.Estimate(password, "lockboxtest")
+		if password != "lockboxtest" {
+			r = strength.Estimate(password)
+		}
+		if r.Entropy >= 20 {
+			t.Errorf("should be weak: %s %f", password, r.Entropy)
+		}
+		got := patterns(r)
+		if len(got) == 0 || got[0] != expect {
+			t.Errorf("invalid patterns: %s %v", password, got)
+		}
+	}
+	r := strength.Estimate("x7$Kq9!mZ2#pL4vR")
+	if r.Entropy < 80 || len(r.Matches) != 0 {
+		t.Errorf("should be strong: %v", r)
+	}
+	if strength.Estimate("Password").Entropy <= strength.Estimate("password").Entropy {
+		t.Error("capitalization should add entropy")
+	}
+}